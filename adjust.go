@@ -0,0 +1,61 @@
+package y4m
+
+import (
+	"fmt"
+	"math"
+)
+
+// Adjustment describes a color adjustment in YCbCr space, applied by Frame.Adjust. Luma is
+// first remapped so that the input black and white points land on the output black and white
+// points, then scaled by Contrast about the midpoint of the output points and offset by
+// Brightness. Chroma is scaled by Saturation about the neutral value 128. Use NewAdjustment
+// to obtain an adjustment that leaves frames unchanged and modify its fields.
+type Adjustment struct {
+	Brightness int     // offset added to luma
+	Contrast   float64 // luma gain; 1 leaves contrast unchanged
+	Saturation float64 // chroma gain; 1 leaves saturation unchanged and 0 gives gray
+	BlackIn    int     // input black point, mapped to BlackOut
+	WhiteIn    int     // input white point, mapped to WhiteOut
+	BlackOut   int     // output black point
+	WhiteOut   int     // output white point
+}
+
+// NewAdjustment returns the adjustment that leaves frames with color range r unchanged, with
+// black and white points at the limits of the luma range.
+func NewAdjustment(r ColorRange) Adjustment {
+	a := Adjustment{Contrast: 1, Saturation: 1, BlackIn: 0, WhiteIn: 255, BlackOut: 0, WhiteOut: 255}
+	if r == ColorRangeLimited {
+		a.BlackIn, a.WhiteIn, a.BlackOut, a.WhiteOut = 16, 235, 16, 235
+	}
+	return a
+}
+
+// Adjust applies color adjustment a to the luma and chroma samples of the frame, which is
+// useful for quick grading of test content and for normalizing sources with mismatched levels
+// before comparing them. Results are clipped to [0, 255]. The alpha plane is unchanged.
+func (f *Frame) Adjust(a Adjustment) error {
+	if a.WhiteIn <= a.BlackIn {
+		return fmt.Errorf("input white point %d is not above black point %d", a.WhiteIn, a.BlackIn)
+	}
+	if a.Contrast < 0 || a.Saturation < 0 {
+		return fmt.Errorf("contrast %g and saturation %g must not be negative", a.Contrast, a.Saturation)
+	}
+	var luma, chroma [256]byte
+	gain := float64(a.WhiteOut-a.BlackOut) / float64(a.WhiteIn-a.BlackIn)
+	mid := float64(a.BlackOut+a.WhiteOut) / 2
+	for v := range luma {
+		y := float64(a.BlackOut) + gain*float64(v-a.BlackIn)
+		y = mid + a.Contrast*(y-mid) + float64(a.Brightness)
+		luma[v] = clampByte(int(math.Round(y)))
+		chroma[v] = clampByte(int(math.Round(128 + a.Saturation*float64(v-128))))
+	}
+	for k, v := range f.Y {
+		f.Y[k] = luma[v]
+	}
+	for _, p := range [][]byte{f.Cb, f.Cr} {
+		for k, v := range p {
+			p[k] = chroma[v]
+		}
+	}
+	return nil
+}
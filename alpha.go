@@ -0,0 +1,69 @@
+package y4m
+
+import "fmt"
+
+// Premultiply multiplies the samples of a frame with an alpha plane by their alpha values, so
+// that luma is scaled towards zero and chroma towards the neutral value 128. Frames are
+// otherwise stored with straight alpha, which is what Overlay and the image conversions
+// assume; premultiplied samples are needed by compositing code and formats that use them, such
+// as OpenEXR or RGBA images. Frames without an alpha plane are unchanged.
+func (f *Frame) Premultiply() {
+	for k, a := range f.Alpha {
+		f.Y[k] = byte((int(f.Y[k])*int(a) + 0x7f) / 0xff)
+		f.Cb[k] = byte(0x80 + roundDiv((int(f.Cb[k])-0x80)*int(a), 0xff))
+		f.Cr[k] = byte(0x80 + roundDiv((int(f.Cr[k])-0x80)*int(a), 0xff))
+	}
+}
+
+// Unpremultiply reverses Premultiply, dividing the samples of a frame with an alpha plane by
+// their alpha values. Fully transparent samples have no color, and are set to black. Precision
+// is lost where alpha is small, so a premultiplied round trip is not exact for translucent
+// samples.
+func (f *Frame) Unpremultiply() {
+	for k, a := range f.Alpha {
+		if a == 0 {
+			f.Y[k], f.Cb[k], f.Cr[k] = 0, 0x80, 0x80
+			continue
+		}
+		f.Y[k] = clampByte(roundDiv(int(f.Y[k])*0xff, int(a)))
+		f.Cb[k] = clampByte(0x80 + roundDiv((int(f.Cb[k])-0x80)*0xff, int(a)))
+		f.Cr[k] = clampByte(0x80 + roundDiv((int(f.Cr[k])-0x80)*0xff, int(a)))
+	}
+}
+
+// AddAlpha converts a 444 frame to 444alpha with every alpha sample set to a. A frame that is
+// already 444alpha has its alpha plane filled with a. Frames in other chroma formats can be
+// converted to 444 with ConvertChroma first.
+func (f *Frame) AddAlpha(a byte) error {
+	alpha := make([]byte, f.Width*f.Height)
+	for k := range alpha {
+		alpha[k] = a
+	}
+	return f.AttachAlpha(alpha)
+}
+
+// AttachAlpha converts a 444 frame to 444alpha with alpha plane alpha, which has one sample per
+// pixel and is used by the frame without copying; a matte read as another frame can be attached
+// with its Y plane. The alpha plane of a frame that is already 444alpha is replaced.
+func (f *Frame) AttachAlpha(alpha []byte) error {
+	if f.Chroma != "444" && f.Chroma != "444alpha" {
+		return fmt.Errorf("cannot attach an alpha plane to a frame with chroma format %s", f.Chroma)
+	}
+	if len(alpha) != f.Width*f.Height {
+		return fmt.Errorf("alpha plane has %d octets, expected %d", len(alpha), f.Width*f.Height)
+	}
+	f.Alpha = alpha
+	f.Chroma = "444alpha"
+	return nil
+}
+
+// RemoveAlpha converts a 444alpha frame to 444 by dropping its alpha plane. The samples are
+// left as they are, so translucent parts of a straight alpha frame keep their color; call
+// Overlay on a background frame first to flatten the frame instead. Frames in other chroma
+// formats are unchanged.
+func (f *Frame) RemoveAlpha() {
+	if f.Chroma == "444alpha" {
+		f.Alpha = nil
+		f.Chroma = "444"
+	}
+}
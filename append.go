@@ -0,0 +1,52 @@
+package y4m
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// OpenAppend opens an existing named stream file for writing further frames to its end, so
+// that a capture can be resumed or extended without rewriting the file. The stream header is
+// parsed into the stream fields, and the frames already in the file are checked so that new
+// frames are not appended after a truncated one. Frames should be written with WriteFrame,
+// which checks them against the stream header. Writes are buffered as for NewStream.
+func OpenAppend(name string) (*Stream, error) {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stream{file: f, Limits: DefaultLimits}
+	err = s.readHeader()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	for {
+		err = s.SkipFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("cannot append to %s: %w", name, err)
+		}
+	}
+	// Skipping the data of a truncated last frame leaves the offset beyond the end of file
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if pos != size {
+		f.Close()
+		return nil, fmt.Errorf("cannot append to %s: %w", name, ErrTruncatedFrame)
+	}
+	s.w = bufio.NewWriterSize(f, defaultWriteBufferSize)
+	return s, nil
+}
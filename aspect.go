@@ -0,0 +1,95 @@
+package y4m
+
+import (
+	"fmt"
+	"io"
+)
+
+// DisplayAspectRatio returns the shape of the displayed picture, the frame size scaled by the
+// sample aspect ratio, in lowest terms: 16:9 for 1920x1080 frames with square samples, or 4:3
+// for 720x576 frames with a sample aspect ratio of 16:15. It returns nil if the sample aspect
+// ratio is unknown.
+func (s *Stream) DisplayAspectRatio() *Ratio {
+	r := s.SampleAspectRatio
+	if r == nil || r.N <= 0 || r.D <= 0 || s.Width <= 0 || s.Height <= 0 {
+		return nil
+	}
+	return (&Ratio{N: s.Width, D: s.Height}).Mul(r)
+}
+
+// SetDisplayAspectRatio sets the sample aspect ratio so that frames of the stream's size are
+// displayed with aspect ratio dar, such as 16:9 or 4:3. This flags anamorphic video, whose
+// samples are not square, without resampling it.
+func (s *Stream) SetDisplayAspectRatio(dar *Ratio) error {
+	if dar == nil || dar.N <= 0 || dar.D <= 0 {
+		return fmt.Errorf("invalid display aspect ratio %v", dar)
+	}
+	if s.Width <= 0 || s.Height <= 0 {
+		return fmt.Errorf("cannot set the display aspect ratio of %dx%d frames", s.Width, s.Height)
+	}
+	s.SampleAspectRatio = dar.Div(&Ratio{N: s.Width, D: s.Height})
+	return nil
+}
+
+// SquarePixelSize returns the size of the stream's frames resampled to square pixels: the
+// width is scaled by the sample aspect ratio and rounded to a multiple of the horizontal
+// chroma subsampling, and the height is kept. The size is unchanged if the sample aspect ratio
+// is unknown.
+func (s *Stream) SquarePixelSize() (w, h int) {
+	r := s.SampleAspectRatio
+	if r == nil || r.N <= 0 || r.D <= 0 || r.N == r.D {
+		return s.Width, s.Height
+	}
+	xss, _, err := subsampling(s.Chroma)
+	if err != nil {
+		xss = 1
+	}
+	n, d := int64(s.Width)*int64(r.N), int64(r.D)*int64(xss)
+	w = int((n + d/2) / d * int64(xss))
+	return maxInt(w, xss), s.Height
+}
+
+// ResampleToSquarePixels writes the frames of stream in to stream out resized with kernel k to
+// the size given by SquarePixelSize, so that their samples are square, as image files and
+// computer displays expect. The header fields of out are taken from in, with the new width
+// and a sample aspect ratio of 1:1, and written before the frames. A stream whose samples are
+// already square, or whose sample aspect ratio is unknown, is copied unchanged.
+func ResampleToSquarePixels(out, in *Stream, k Kernel) error {
+	w, h := in.SquarePixelSize()
+	out.Width = w
+	out.Height = h
+	out.Chroma = in.Chroma
+	out.FrameRate = in.FrameRate
+	out.Interlacing = in.Interlacing
+	out.SampleAspectRatio = in.SampleAspectRatio
+	if r := in.SampleAspectRatio; r != nil && r.N > 0 && r.D > 0 {
+		out.SampleAspectRatio = &Ratio{N: 1, D: 1}
+	}
+	out.CopyTags(in)
+	out.XSubsamplingFactor = in.XSubsamplingFactor
+	out.YSubsamplingFactor = in.YSubsamplingFactor
+	err := out.WriteHeader()
+	if err != nil {
+		return err
+	}
+	if w == in.Width {
+		_, err = CopyFrames(out, in, -1)
+		return err
+	}
+	for {
+		f, err := in.ParseFrame()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		err = f.Resize(w, h, k)
+		if err != nil {
+			return err
+		}
+		err = out.WriteFrame(f)
+		if err != nil {
+			return err
+		}
+	}
+}
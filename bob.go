@@ -0,0 +1,69 @@
+package y4m
+
+import (
+	"fmt"
+)
+
+// Bob deinterlaces the frame by line doubling each of its fields, returning two progressive
+// frames of the original size in display order. topFirst gives the field order, as reported
+// by Stream.TopFieldFirst. The frames' headers are copies of the frame's header without an I
+// field.
+func (f *Frame) Bob(topFirst bool) (first, second *Frame, err error) {
+	top, bottom, err := f.Fields()
+	if err != nil {
+		return nil, nil, err
+	}
+	frames := [2]*Frame{}
+	for k, field := range []*Frame{top, bottom} {
+		g := &Frame{Header: progressiveHeader(f.Header), Width: f.Width, Height: f.Height, Chroma: f.Chroma}
+		g.Y = doubleLines(field.Plane(PlaneY))
+		g.Cb = doubleLines(field.Plane(PlaneCb))
+		g.Cr = doubleLines(field.Plane(PlaneCr))
+		g.Alpha = doubleLines(field.Plane(PlaneAlpha))
+		frames[k] = g
+	}
+	if !topFirst {
+		return frames[1], frames[0], nil
+	}
+	return frames[0], frames[1], nil
+}
+
+// Bob updates the header fields of an output stream to hold the bobbed frames of an interlaced
+// stream: the stream becomes progressive and its frame rate is doubled. It should be called
+// on an output stream before its header is written.
+func (s *Stream) Bob() error {
+	if s.Interlacing == "p" {
+		return fmt.Errorf("cannot bob a progressive stream")
+	}
+	s.Interlacing = "p"
+	if s.FrameRate != nil {
+		s.FrameRate = s.FrameRate.Mul(&Ratio{N: 2, D: 1})
+	}
+	return nil
+}
+
+// doubleLines returns a packed plane of twice the height of plane p, with each line repeated.
+// An absent plane gives nil.
+func doubleLines(p Plane) []byte {
+	if p.Data == nil {
+		return nil
+	}
+	out := make([]byte, 0, 2*p.Width*p.Height)
+	for y := 0; y < p.Height; y++ {
+		out = append(out, p.Row(y)...)
+		out = append(out, p.Row(y)...)
+	}
+	return out
+}
+
+// progressiveHeader returns a copy of frame header h without an I field, for a frame produced
+// by deinterlacing.
+func progressiveHeader(h *FrameHeader) *FrameHeader {
+	g := h.Copy()
+	if g == nil {
+		g = &FrameHeader{MagicString: "FRAME"}
+	}
+	g.I = nil
+	g.Raw = g.Bytes()
+	return g
+}
@@ -0,0 +1,51 @@
+package y4m
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// BurnInOptions configures BurnIn.
+type BurnInOptions struct {
+	FrameNumber bool // render the frame number
+	Timecode    bool // render the timecode, which requires a known frame rate
+	DropFrame   bool // render drop-frame timecode, which requires an NTSC frame rate
+	X           int  // position of the left edge of the label in luma samples
+	Y           int  // position of the top edge of the label in luma samples
+	// Scale is the size of each pixel of the 5x7 font in luma samples. Zero chooses a scale
+	// that suits the frame height.
+	Scale int
+}
+
+// BurnIn renders the number n of the frame, counting from zero, and the corresponding timecode
+// at frame rate rate into the frame, as a label of white digits on a black box. Burnt-in numbers
+// are the usual way to check synchronization and seeking visually. The timecode is given by
+// NewTimecode.
+func (f *Frame) BurnIn(n int, rate *Ratio, o BurnInOptions) error {
+	var parts []string
+	if o.FrameNumber {
+		parts = append(parts, strconv.Itoa(n))
+	}
+	if o.Timecode {
+		tc, err := NewTimecode(n, rate, o.DropFrame)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, tc.String())
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	scale := o.Scale
+	if scale <= 0 {
+		scale = maxInt(1, f.Height/180)
+	}
+	DrawText(f, strings.Join(parts, " "), o.X, o.Y, TextOptions{
+		Scale:      scale,
+		Color:      color.YCbCr{Y: 235, Cb: 128, Cr: 128},
+		Box:        true,
+		Background: color.YCbCr{Y: 16, Cb: 128, Cr: 128},
+	})
+	return nil
+}
@@ -0,0 +1,100 @@
+package y4m
+
+import (
+	"image"
+)
+
+// ColorRange is the range of sample values used by a stream.
+type ColorRange int
+
+// Values of the XCOLORRANGE stream header tag.
+const (
+	// ColorRangeUnspecified indicates that the stream has no XCOLORRANGE tag.
+	ColorRangeUnspecified ColorRange = iota
+	// ColorRangeLimited indicates studio range samples: luma 16-235 and chroma 16-240.
+	ColorRangeLimited
+	// ColorRangeFull indicates full range samples: 0-255.
+	ColorRangeFull
+)
+
+var colorRangeNames = map[ColorRange]string{
+	ColorRangeLimited: "LIMITED",
+	ColorRangeFull:    "FULL",
+}
+
+// String returns the XCOLORRANGE tag value for the range, or "unspecified".
+func (r ColorRange) String() string {
+	if n, ok := colorRangeNames[r]; ok {
+		return n
+	}
+	return "unspecified"
+}
+
+// ConvertRange rescales the frame's luma and chroma samples from range from to range to. The
+// alpha plane is unchanged. An unspecified range is treated as full range.
+func (f *Frame) ConvertRange(from, to ColorRange) {
+	if from != ColorRangeLimited {
+		from = ColorRangeFull
+	}
+	if to != ColorRangeLimited {
+		to = ColorRangeFull
+	}
+	if from == to {
+		return
+	}
+	for k, v := range f.Y {
+		if to == ColorRangeFull {
+			f.Y[k] = clampByte(roundDiv((int(v)-16)*255, 219))
+		} else {
+			f.Y[k] = byte(16 + roundDiv(int(v)*219, 255))
+		}
+	}
+	for _, p := range [][]byte{f.Cb, f.Cr} {
+		for k, v := range p {
+			if to == ColorRangeFull {
+				p[k] = clampByte(128 + roundDiv((int(v)-128)*255, 224))
+			} else {
+				p[k] = byte(128 + roundDiv((int(v)-128)*224, 255))
+			}
+		}
+	}
+}
+
+// ImageRange is like Image, but for a frame whose samples use range r. Limited range samples
+// are expanded to the full range that the image color models assume, so the image does not
+// share the frame's planes.
+func (f *Frame) ImageRange(r ColorRange) (image.Image, error) {
+	if r != ColorRangeLimited {
+		return f.Image()
+	}
+	g := f.Copy()
+	g.ConvertRange(ColorRangeLimited, ColorRangeFull)
+	return g.Image()
+}
+
+// FrameFromImageRange is like FrameFromImage, but produces a frame whose samples use range r.
+func FrameFromImageRange(img image.Image, chroma string, r ColorRange) (*Frame, error) {
+	f, err := FrameFromImage(img, chroma)
+	if err != nil {
+		return nil, err
+	}
+	f.ConvertRange(ColorRangeFull, r)
+	return f, nil
+}
+
+func clampByte(v int) byte {
+	if v < 0 {
+		return 0
+	} else if v > 0xff {
+		return 0xff
+	}
+	return byte(v)
+}
+
+// roundDiv returns a/b rounded to the nearest integer, with halves rounded away from zero.
+func roundDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
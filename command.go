@@ -0,0 +1,96 @@
+package y4m
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+)
+
+// commandError describes the failure err of the named external command, including the
+// messages it wrote to stderr, if any.
+func commandError(name string, err error, stderr []byte) error {
+	if msg := bytes.TrimSpace(stderr); len(msg) > 0 {
+		return fmt.Errorf("%s failed: %w: %s", name, err, msg)
+	}
+	return fmt.Errorf("%s failed: %w", name, err)
+}
+
+// commandWriter writes to the standard input of a process, and reports the failure of the
+// process in place of the broken pipe that results when it exits early.
+type commandWriter struct {
+	stdin  io.WriteCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer // captured stderr, or nil if the caller set cmd.Stderr
+	done   bool
+	err    error // result of the process, once done
+}
+
+func (c *commandWriter) Write(b []byte) (int, error) {
+	if c.done {
+		return 0, c.err
+	}
+	n, err := c.stdin.Write(b)
+	if err != nil {
+		c.wait()
+		if c.err != nil {
+			return n, c.err
+		}
+	}
+	return n, err
+}
+
+// wait closes the standard input of the process and waits for it to exit.
+func (c *commandWriter) wait() {
+	if c.done {
+		return
+	}
+	c.done = true
+	c.stdin.Close()
+	err := c.cmd.Wait()
+	if err != nil {
+		var stderr []byte
+		if c.stderr != nil {
+			stderr = c.stderr.Bytes()
+		}
+		c.err = commandError(filepath.Base(c.cmd.Path), err, stderr)
+	}
+}
+
+// Close closes the standard input of the process, which ends the stream, and waits for the
+// process to exit.
+func (c *commandWriter) Close() error {
+	c.wait()
+	return c.err
+}
+
+// NewCommandWriter starts command cmd, such as an x264 or ffmpeg encoder reading y4m from
+// standard input, and creates a new stream with width w and height h that is written to its
+// standard input. Writes are buffered as with NewStream. Close flushes the buffer, closes the
+// standard input of the command and waits for it to finish, returning an error if it fails.
+// If the command exits early, writes return its failure. Unless cmd.Stderr is set, the
+// messages the command writes to stderr are included in the errors.
+func NewCommandWriter(cmd *exec.Cmd, w, h int) (*Stream, error) {
+	c := &commandWriter{cmd: cmd}
+	if cmd.Stderr == nil {
+		c.stderr = new(bytes.Buffer)
+		cmd.Stderr = c.stderr
+	}
+	var err error
+	c.stdin, err = cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("cannot run %s: %w", filepath.Base(cmd.Path), err)
+	}
+	s := new(Stream)
+	s.w = bufio.NewWriterSize(c, defaultWriteBufferSize)
+	s.closer = c
+	s.Width = w
+	s.Height = h
+	return s, nil
+}
@@ -0,0 +1,171 @@
+package y4m
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// ConcatOptions selects how ConcatStreamsWith treats input streams that differ from the first,
+// and whether it crossfades between them.
+type ConcatOptions struct {
+	// ConvertChroma converts frames to the chroma format of the first stream.
+	ConvertChroma bool
+	// Fit centres frames on the geometry of the first stream, cropping them or padding them
+	// with black as needed.
+	Fit bool
+	// IgnoreRate accepts streams with a different frame rate. Their frames are displayed at
+	// the rate of the first stream.
+	IgnoreRate bool
+	// Crossfade blends the last Crossfade frames of each stream with the first Crossfade
+	// frames of the next, so that the output is shorter by Crossfade frames per join. The
+	// frames of the stream that follows have weights rising from 1/(Crossfade+1) to
+	// Crossfade/(Crossfade+1), shaped by Easing.
+	Crossfade int
+	Easing    Easing
+}
+
+// ConcatStreams writes the frames of streams ins, one after another, to stream out. The input
+// streams must agree in geometry, chroma format and frame rate. The header fields of out are
+// taken from the first input stream and written once, before any frames. If the inputs
+// disagree on the interlacing mode, the output is mixed-mode ("m") and frames without an I
+// field are given the one implied by the mode of their stream, or the mode is unknown ("?") if
+// that of an input is. Other frames are copied verbatim with CopyFrames.
+func ConcatStreams(out *Stream, ins ...*Stream) error {
+	return ConcatStreamsWith(out, ConcatOptions{}, ins...)
+}
+
+// ConcatStreamsWith is like ConcatStreams, but input streams that differ from the first are
+// converted as selected by opts, one frame at a time. With a crossfade, every stream but the first and last must
+// have at least twice as many frames as the crossfade, and those two at least as many.
+func ConcatStreamsWith(out *Stream, opts ConcatOptions, ins ...*Stream) error {
+	if len(ins) == 0 {
+		return fmt.Errorf("no input streams to concatenate")
+	}
+	first := ins[0]
+	interlacing := first.Interlacing
+	for k, s := range ins[1:] {
+		err := checkCompatible(first, s, opts)
+		if err != nil {
+			return fmt.Errorf("input stream %d: %w", k+2, err)
+		}
+		if s.Interlacing != interlacing {
+			interlacing = "m"
+		}
+	}
+	for _, s := range ins {
+		if interlacing == "m" && s.Interlacing != "m" && s.IField() == nil {
+			interlacing = "?"
+		}
+	}
+	out.Width = first.Width
+	out.Height = first.Height
+	out.Chroma = first.Chroma
+	out.FrameRate = first.FrameRate
+	out.Interlacing = interlacing
+	out.SampleAspectRatio = first.SampleAspectRatio
+	out.CopyTags(first)
+	out.XSubsamplingFactor = first.XSubsamplingFactor
+	out.YSubsamplingFactor = first.YSubsamplingFactor
+	err := out.WriteHeader()
+	if err != nil {
+		return err
+	}
+	// tail holds the last frames of the previous stream, to be blended with the first frames
+	// of the current one
+	var tail []*Frame
+	for k, s := range ins {
+		err = s.ToFirstFrame()
+		if err != nil {
+			return err
+		}
+		// The frames of a stream joined to a mixed-mode output may need an I field
+		stamp := interlacing == "m" && s.Interlacing != "m"
+		if opts.Crossfade == 0 && s.Width == out.Width && s.Height == out.Height && s.Chroma == out.Chroma &&
+			!stamp {
+			_, err = CopyFrames(out, s, -1)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		last := k == len(ins)-1
+		var held []*Frame
+		n := 0
+		for ; ; n++ {
+			f, err := s.ParseFrame()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			f, err = conform(f, out, opts)
+			if err != nil {
+				return err
+			}
+			if stamp && (f.Header == nil || f.Header.I == nil) {
+				i := s.IField()
+				f.SetI(i.Presentation, i.Temporal, i.Spatial)
+			}
+			if n < len(tail) {
+				t := opts.Easing.Weight(float64(n+1) / float64(len(tail)+1))
+				f, err = Crossfade(tail[n], f, t)
+				if err != nil {
+					return err
+				}
+			} else if !last && opts.Crossfade > 0 {
+				held = append(held, f)
+				if len(held) <= opts.Crossfade {
+					continue
+				}
+				f, held = held[0], held[1:]
+			}
+			err = out.WriteFrame(f)
+			if err != nil {
+				return err
+			}
+		}
+		if n < len(tail) || (!last && len(held) < opts.Crossfade) {
+			return fmt.Errorf("input stream %d has too few frames to crossfade over %d frames",
+				k+1, opts.Crossfade)
+		}
+		tail = held
+	}
+	return nil
+}
+
+// checkCompatible checks that the frames of stream t can follow those of stream s, after any
+// conversions selected by opts.
+func checkCompatible(s, t *Stream, opts ConcatOptions) error {
+	if (!opts.Fit && (s.Width != t.Width || s.Height != t.Height)) ||
+		(!opts.ConvertChroma && s.Chroma != t.Chroma) {
+		return fmt.Errorf("%dx%d %s frames do not match %dx%d %s frames",
+			t.Width, t.Height, t.Chroma, s.Width, s.Height, s.Chroma)
+	}
+	if !opts.IgnoreRate && s.FrameRate.Cmp(t.FrameRate) != 0 {
+		return fmt.Errorf("frame rate %v does not match %v", t.FrameRate, s.FrameRate)
+	}
+	return nil
+}
+
+// conform converts frame f to the chroma format and geometry of stream s as selected by opts.
+func conform(f *Frame, s *Stream, opts ConcatOptions) (*Frame, error) {
+	if opts.ConvertChroma && f.Chroma != s.Chroma {
+		g, err := f.ConvertChroma(s.Chroma)
+		if err != nil {
+			return nil, err
+		}
+		f = g
+	}
+	if opts.Fit && (f.Width != s.Width || f.Height != s.Height) {
+		black := color.YCbCr{Y: 0, Cb: 0x80, Cr: 0x80}
+		if s.ColorRange == ColorRangeLimited {
+			black.Y = 16
+		}
+		err := f.Fit(s.Width, s.Height, black)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
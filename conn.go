@@ -0,0 +1,159 @@
+package y4m
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+)
+
+// Framing selects how a stream is carried over a network connection by NewConnWriter,
+// OpenConn and SendStream.
+type Framing int
+
+const (
+	// RawFraming sends the stream as it would be stored in a file. The receiver sees the end of
+	// the stream when the connection is closed, and cannot tell it from a dropped connection.
+	RawFraming Framing = iota
+	// LengthFraming sends the stream in chunks, each preceded by its length in octets as a
+	// 32-bit big-endian integer, and ends it with an empty chunk. The receiver can tell a
+	// complete stream from a dropped connection, and the connection remains usable after the
+	// end of the stream.
+	LengthFraming
+)
+
+// chunkWriter writes each write to w as a length-framed chunk.
+type chunkWriter struct {
+	w io.Writer
+}
+
+func (cw chunkWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		// An empty chunk would end the stream
+		return 0, nil
+	}
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+	_, err := cw.w.Write(n[:])
+	if err != nil {
+		return 0, err
+	}
+	return cw.w.Write(b)
+}
+
+// end writes the empty chunk that ends the stream.
+func (cw chunkWriter) end() error {
+	_, err := cw.w.Write(make([]byte, 4))
+	return err
+}
+
+// chunkReader reads the data of the length-framed chunks read from r, up to the empty chunk
+// that ends the stream.
+type chunkReader struct {
+	r    io.Reader
+	left uint32 // octets remaining in the current chunk
+	done bool
+}
+
+func (cr *chunkReader) Read(b []byte) (int, error) {
+	if cr.done {
+		return 0, io.EOF
+	}
+	if cr.left == 0 {
+		var n [4]byte
+		_, err := io.ReadFull(cr.r, n[:])
+		if err == io.EOF {
+			// The connection was closed without ending the stream
+			return 0, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return 0, err
+		}
+		cr.left = binary.BigEndian.Uint32(n[:])
+		if cr.left == 0 {
+			cr.done = true
+			return 0, io.EOF
+		}
+	}
+	if uint32(len(b)) > cr.left {
+		b = b[:cr.left]
+	}
+	n, err := cr.r.Read(b)
+	cr.left -= uint32(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// connCloser ends a length-framed stream and closes its connection.
+type connCloser struct {
+	c  net.Conn
+	cw chunkWriter
+}
+
+func (cc connCloser) Close() error {
+	err := cc.cw.end()
+	if cerr := cc.c.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewConnWriter creates a new stream with width w and height h that is sent over connection
+// c, such as a TCP or Unix domain socket connection, with the given framing. Writes are
+// buffered as with NewStream; call Flush to send buffered frames immediately, as a live
+// source should after each frame. Close flushes the buffer, ends the stream and closes c.
+func NewConnWriter(c net.Conn, framing Framing, w, h int) *Stream {
+	s := new(Stream)
+	if framing == LengthFraming {
+		cw := chunkWriter{c}
+		s.w = bufio.NewWriterSize(cw, defaultWriteBufferSize)
+		s.closer = connCloser{c, cw}
+	} else {
+		s.w = bufio.NewWriterSize(c, defaultWriteBufferSize)
+		s.closer = c
+	}
+	s.Width = w
+	s.Height = h
+	return s
+}
+
+// OpenConn parses the header of a stream received over connection c with the given framing.
+// As with OpenReader, the frames can only be read in order. ParseFrame returns io.EOF at the
+// end of the stream; with LengthFraming, a connection closed before the end of the stream
+// gives an error instead. Close closes c.
+func OpenConn(c net.Conn, framing Framing) (*Stream, error) {
+	var r io.Reader = c
+	if framing == LengthFraming {
+		r = &chunkReader{r: c}
+	}
+	s, err := OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	s.closer = c
+	return s, nil
+}
+
+// SendStream sends the header and the remaining frames of stream s over connection c with
+// the given framing, and ends the stream. With RawFraming, the stream ends by closing c; with
+// LengthFraming, c is left open.
+func SendStream(c net.Conn, s *Stream, framing Framing) error {
+	var w io.Writer = c
+	if framing == LengthFraming {
+		w = chunkWriter{c}
+	}
+	bw := bufio.NewWriterSize(w, defaultWriteBufferSize)
+	err := copyStream(bw, s, nil)
+	if err != nil {
+		return err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return err
+	}
+	if framing == LengthFraming {
+		return chunkWriter{c}.end()
+	}
+	return c.Close()
+}
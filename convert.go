@@ -0,0 +1,108 @@
+package y4m
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// FrameFromImage converts img into a frame in the given chroma format. YCbCr, NYCbCrA and Gray
+// images are read directly; other images, such as RGBA, are converted to YCbCr using the
+// standard library's JFIF conversion. Chroma is downsampled by averaging each block of
+// samples, and alpha is taken from the image when converting to 444alpha. The frame header is
+// a bare "FRAME" header.
+func FrameFromImage(img image.Image, chroma string) (*Frame, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	f, err := NewFrame(w, h, chroma)
+	if err != nil {
+		return nil, err
+	}
+	var cb, cr []byte
+	if len(f.Cb) > 0 {
+		cb = make([]byte, w*h)
+		cr = make([]byte, w*h)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			k := y*w + x
+			var yy, u, v, a uint8
+			switch m := img.(type) {
+			case *image.NYCbCrA:
+				yi, ci := m.YOffset(b.Min.X+x, b.Min.Y+y), m.COffset(b.Min.X+x, b.Min.Y+y)
+				yy, u, v, a = m.Y[yi], m.Cb[ci], m.Cr[ci], m.A[m.AOffset(b.Min.X+x, b.Min.Y+y)]
+			case *image.YCbCr:
+				yi, ci := m.YOffset(b.Min.X+x, b.Min.Y+y), m.COffset(b.Min.X+x, b.Min.Y+y)
+				yy, u, v, a = m.Y[yi], m.Cb[ci], m.Cr[ci], 0xff
+			case *image.Gray:
+				yy, u, v, a = m.GrayAt(b.Min.X+x, b.Min.Y+y).Y, 0x80, 0x80, 0xff
+			default:
+				c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+				yy, u, v = color.RGBToYCbCr(c.R, c.G, c.B)
+				a = c.A
+			}
+			f.Y[k] = yy
+			if cb != nil {
+				cb[k], cr[k] = u, v
+			}
+			if len(f.Alpha) > 0 {
+				f.Alpha[k] = a
+			}
+		}
+	}
+	if cb != nil {
+		xss, yss := xSubsamplingFactor[chroma], ySubsamplingFactor[chroma]
+		downsample(f.Cb, cb, w, h, xss, yss)
+		downsample(f.Cr, cr, w, h, xss, yss)
+	}
+	return f, nil
+}
+
+// downsample reduces plane src, of width w and height h, by factors xss and yss into dst by
+// averaging each block of samples. Blocks at the right and bottom edges of a plane whose size
+// is not a multiple of the factors average the samples they cover.
+func downsample(dst, src []byte, w, h, xss, yss int) {
+	cw, ch := (w+xss-1)/xss, (h+yss-1)/yss
+	for cy := 0; cy < ch; cy++ {
+		for cx := 0; cx < cw; cx++ {
+			sum, n := 0, 0
+			for y := cy * yss; y < minInt((cy+1)*yss, h); y++ {
+				for x := cx * xss; x < minInt((cx+1)*xss, w); x++ {
+					sum += int(src[y*w+x])
+					n++
+				}
+			}
+			dst[cy*cw+cx] = byte((sum + n/2) / n)
+		}
+	}
+}
+
+// ConvertChroma returns a copy of the frame converted to the given chroma format. Chroma is
+// resampled as by FrameFromImage. The copy has a copy of the frame's header.
+func (f *Frame) ConvertChroma(chroma string) (*Frame, error) {
+	img, err := f.Image()
+	if err != nil {
+		return nil, err
+	}
+	g, err := FrameFromImage(img, chroma)
+	if err != nil {
+		return nil, err
+	}
+	g.Header = f.Header.Copy()
+	return g, nil
+}
+
+// ConvertChroma sets the chroma format of an output stream that holds frames converted with
+// Frame.ConvertChroma. An XYSCSS tag, such as one copied from the input stream, is rewritten to
+// name the new format as mjpegtools does, so that it does not contradict the header's C field.
+// It should be called on an output stream before its header is written.
+func (s *Stream) ConvertChroma(chroma string) error {
+	err := s.SetChroma(chroma)
+	if err != nil {
+		return err
+	}
+	if s.YSCSS != "" {
+		s.YSCSS = strings.ToUpper(chroma)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package y4m
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteTo writes the remaining frames of the stream, from the read offset to the end, to w
+// unchanged, implementing io.WriterTo. The frame headers are parsed but the planes are not:
+// the frames of a stream file are copied as a single byte range, which io.Copy can pass to the
+// operating system when w is a file, and those of a stream that cannot seek are copied frame by
+// frame. The stream header is not written. It returns the number of octets written.
+func (s *Stream) WriteTo(w io.Writer) (int64, error) {
+	return s.copyFrames(w, -1)
+}
+
+// ReadFrom writes the data read from r until EOF to the stream unchanged, implementing
+// io.ReaderFrom. The data must be frames, headers and planes, in the format of the stream,
+// such as the output of WriteTo; it is not checked. The stream header should be written
+// beforehand. It returns the number of octets read.
+func (s *Stream) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(s.writer(), r)
+}
+
+// CopyFrames copies the next n frames of stream src to stream dst verbatim, frame headers and
+// planes, or all the remaining frames if n is -1. The planes are neither parsed nor held in
+// frames, and the frames of a stream file are copied as a single byte range, which makes
+// lossless trims and concatenations of large files much faster than ParseFrame and
+// WriteFrame. The streams must have the same geometry and chroma format, and the header of dst
+// should be written beforehand. It returns the number of frames copied; if src ends before n
+// frames, the frames that remain are copied and io.EOF is returned.
+func CopyFrames(dst, src *Stream, n int) (int, error) {
+	if dst.Width != src.Width || dst.Height != src.Height || dst.Chroma != src.Chroma {
+		return 0, fmt.Errorf("cannot copy %dx%d %s frames into %dx%d %s stream",
+			src.Width, src.Height, src.Chroma, dst.Width, dst.Height, dst.Chroma)
+	}
+	if n < -1 {
+		return 0, fmt.Errorf("invalid number of frames %d", n)
+	}
+	start := src.frameIndex
+	end := -1
+	if n >= 0 {
+		end = start + n
+	}
+	_, err := src.copyFrames(dst.writer(), end)
+	return src.frameIndex - start, err
+}
+
+// copyFrames copies the frames from the read offset up to but not including frame end,
+// counting from zero, or through the last frame if end is -1, to w without parsing their
+// planes. If the stream ends before frame end, the frames that remain are copied and io.EOF
+// is returned. The stream is left positioned after the last frame copied.
+func (s *Stream) copyFrames(w io.Writer, end int) (int64, error) {
+	if s.in != nil {
+		return s.copyFramesReader(w, end)
+	}
+	from, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	last := from
+	var short error
+	for end == -1 || s.frameIndex < end {
+		pos, err := s.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		err = s.SkipFrame()
+		if err == io.EOF && end != -1 {
+			short = io.EOF
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		last = pos
+	}
+	to, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, io.NewSectionReader(s.file, from, to-from))
+	if err != nil {
+		return n, err
+	}
+	if n != to-from {
+		// The data of the last frame extends beyond the end of file
+		return n, newFrameError(s.frameIndex-1, last, ErrTruncatedFrame)
+	}
+	return n, short
+}
+
+// copyFramesReader is copyFrames for a stream that cannot seek.
+func (s *Stream) copyFramesReader(w io.Writer, end int) (int64, error) {
+	var n int64
+	for end == -1 || s.frameIndex < end {
+		offset := s.pos
+		hs, err := readLine(s.in, s.Limits.MaxHeaderLength)
+		s.pos += int64(len(hs))
+		if err == io.EOF && len(hs) == 0 && end == -1 {
+			break
+		} else if err == io.EOF && len(hs) == 0 {
+			return n, io.EOF
+		} else if err == io.EOF {
+			return n, s.frameError(offset, ErrTruncatedFrame)
+		} else if err != nil {
+			return n, s.frameError(offset, err)
+		}
+		_, err = s.parseFrameLine(hs)
+		if err != nil {
+			return n, s.frameError(offset, err)
+		}
+		m, err := w.Write(hs)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+		k, err := io.CopyN(w, s.in, s.FrameImageDataSize())
+		s.pos += k
+		n += k
+		if err == io.EOF {
+			return n, newFrameError(s.frameIndex, offset, ErrTruncatedFrame)
+		} else if err != nil {
+			return n, err
+		}
+		s.frameIndex++
+	}
+	return n, nil
+}
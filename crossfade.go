@@ -0,0 +1,51 @@
+package y4m
+
+import (
+	"fmt"
+	"math"
+)
+
+// Easing selects how the weight of the incoming frames grows over a crossfade.
+type Easing int
+
+const (
+	// EaseLinear increases the weight at a constant rate.
+	EaseLinear Easing = iota
+	// EaseInOut starts and ends the transition slowly, following a smoothstep curve.
+	EaseInOut
+)
+
+func (e Easing) String() string {
+	switch e {
+	case EaseLinear:
+		return "linear"
+	case EaseInOut:
+		return "ease-in-out"
+	}
+	return fmt.Sprintf("Easing(%d)", int(e))
+}
+
+// Weight returns the eased weight at position t in [0, 1] of a transition.
+func (e Easing) Weight(t float64) float64 {
+	t = math.Max(0, math.Min(1, t))
+	if e == EaseInOut {
+		return t * t * (3 - 2*t)
+	}
+	return t
+}
+
+// Crossfade returns a frame mixing frames f and g, with weight t in [0, 1] given to g, so that
+// t = 0 gives f and t = 1 gives g. Every plane, including alpha, is mixed. The frames must
+// have the same geometry and chroma format, and the frame header is copied from f.
+func Crossfade(f, g *Frame, t float64) (*Frame, error) {
+	err := checkComparable(f, g)
+	if err != nil {
+		return nil, err
+	}
+	// Mix in fixed point with 8 fractional bits
+	n := int64(math.Round(256 * math.Max(0, math.Min(1, t))))
+	if n == 0 {
+		return f.Copy(), nil
+	}
+	return blendFrames(f, g, n, 256), nil
+}
@@ -0,0 +1,109 @@
+package y4m
+
+import (
+	"fmt"
+)
+
+// Deinterlace deinterlaces frame cur with a motion-adaptive filter in the style of yadif,
+// returning one progressive frame of the same size. The field displayed first, as given by
+// topFirst, is kept, and the lines of the other field are interpolated. Where the picture is
+// still, the missing lines are taken from the neighbouring fields of prev and cur; where it
+// moves, they are predicted spatially from the kept lines along the strongest nearby edge.
+// prev and next are the frames before and after cur in display order, and either may be nil
+// at the ends of a stream. The frame's header is copied without its I field.
+func Deinterlace(prev, cur, next *Frame, topFirst bool) (*Frame, error) {
+	if prev == nil {
+		prev = cur
+	}
+	if next == nil {
+		next = cur
+	}
+	for _, f := range []*Frame{prev, next} {
+		if f.Width != cur.Width || f.Height != cur.Height || f.Chroma != cur.Chroma {
+			return nil, fmt.Errorf("cannot deinterlace %dx%d %s frame using %dx%d %s neighbour",
+				cur.Width, cur.Height, cur.Chroma, f.Width, f.Height, f.Chroma)
+		}
+	}
+	_, yss, err := subsampling(cur.Chroma)
+	if err != nil {
+		return nil, err
+	}
+	if cur.Height < 2*yss {
+		return nil, fmt.Errorf("cannot deinterlace %s frame of height %d", cur.Chroma, cur.Height)
+	}
+	// The missing lines belong to the second field, which in prev is displayed just before
+	// the kept field and in cur just after it.
+	parity := 1
+	if !topFirst {
+		parity = 0
+	}
+	out := &Frame{Header: progressiveHeader(cur.Header), Width: cur.Width, Height: cur.Height, Chroma: cur.Chroma}
+	dst := []*[]byte{&out.Y, &out.Cb, &out.Cr, &out.Alpha}
+	for i := PlaneY; i <= PlaneAlpha; i++ {
+		if cur.Plane(i).Data == nil {
+			continue
+		}
+		*dst[i] = deinterlacePlane(prev.Plane(i), cur.Plane(i), next.Plane(i), parity)
+	}
+	return out, nil
+}
+
+// deinterlacePlane returns a packed copy of plane cur in which the lines of the given parity
+// have been interpolated.
+func deinterlacePlane(prev, cur, next Plane, parity int) []byte {
+	w, h := cur.Width, cur.Height
+	out := copyBytes(cur.Packed())
+	// Lines of the missing field immediately before and after the kept field
+	prev2, next2 := prev, cur
+	line := func(y int) int {
+		if y < 0 {
+			return y + 2
+		} else if y >= h {
+			return y - 2
+		}
+		return y
+	}
+	col := func(x int) int {
+		return maxInt(0, minInt(x, w-1))
+	}
+	for y := parity; y < h; y += 2 {
+		above, below := cur.Row(line(y-1)), cur.Row(line(y+1))
+		for x := 0; x < w; x++ {
+			c, e := int(above[x]), int(below[x])
+			p2, n2 := int(prev2.At(x, y)), int(next2.At(x, y))
+			d := (p2 + n2) / 2
+			// Temporal change of the missing field, and of the kept field around the line
+			diff := maxInt(absInt(p2-n2)/2, maxInt(
+				(absInt(int(prev.At(x, line(y-1)))-c)+absInt(int(prev.At(x, line(y+1)))-e))/2,
+				(absInt(int(next.At(x, line(y-1)))-c)+absInt(int(next.At(x, line(y+1)))-e))/2))
+			// Spatial prediction along whichever of three directions matches best
+			pred, best := (c+e)/2, -1
+			for j := -1; j <= 1; j++ {
+				score := 0
+				for k := -1; k <= 1; k++ {
+					score += absInt(int(above[col(x+k+j)]) - int(below[col(x+k-j)]))
+				}
+				if best < 0 || score < best {
+					pred, best = (int(above[col(x+j)])+int(below[col(x-j)]))/2, score
+				}
+			}
+			// Allow more spatial freedom where the missing field disagrees with its
+			// vertical neighbours two lines away
+			b := (int(prev2.At(x, line(y-2))) + int(next2.At(x, line(y-2)))) / 2
+			f := (int(prev2.At(x, line(y+2))) + int(next2.At(x, line(y+2)))) / 2
+			hi := maxInt(maxInt(d-e, d-c), minInt(b-c, f-e))
+			lo := minInt(minInt(d-e, d-c), maxInt(b-c, f-e))
+			diff = maxInt(diff, maxInt(lo, -hi))
+			pred = maxInt(d-diff, minInt(pred, d+diff))
+			out[y*w+x] = byte(maxInt(0, minInt(pred, 0xff)))
+		}
+	}
+	return out
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,102 @@
+package y4m
+
+import "fmt"
+
+// TemporalAverage returns a denoised copy of frame frames[center], in which each luma and
+// chroma sample is replaced by the mean of the co-located samples of all the frames. If
+// threshold is positive, samples of other frames that differ from the sample of the centre
+// frame by more than threshold are left out of the mean, so that moving edges are not smeared
+// across frames; a threshold of zero averages every sample. The alpha plane and the frame
+// header are copied from the centre frame.
+func TemporalAverage(frames []*Frame, center, threshold int) (*Frame, error) {
+	if center < 0 || center >= len(frames) {
+		return nil, fmt.Errorf("centre frame %d is outside the %d frames to average", center, len(frames))
+	}
+	c := frames[center]
+	for _, f := range frames {
+		err := checkComparable(c, f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	out := c.Copy()
+	average := func(dst []byte, plane func(f *Frame) []byte) {
+		for k, v := range dst {
+			sum, n := 0, 0
+			for _, f := range frames {
+				s := plane(f)[k]
+				if threshold > 0 && absInt(int(s)-int(v)) > threshold {
+					continue
+				}
+				sum += int(s)
+				n++
+			}
+			dst[k] = byte((sum + n/2) / n)
+		}
+	}
+	average(out.Y, func(f *Frame) []byte { return f.Y })
+	average(out.Cb, func(f *Frame) []byte { return f.Cb })
+	average(out.Cr, func(f *Frame) []byte { return f.Cr })
+	return out, nil
+}
+
+// TemporalDenoiser applies TemporalAverage to a sequence of frames over a sliding window that
+// extends radius frames either side of each frame, shrinking at the ends of the sequence.
+// Frames are returned in order, radius frames behind the input.
+type TemporalDenoiser struct {
+	radius    int
+	threshold int
+	window    []*Frame // input frames around the next frame to return
+	center    int      // index in window of the next frame to return
+}
+
+// NewTemporalDenoiser returns a TemporalDenoiser averaging over windows of 2*radius+1 frames,
+// with the motion threshold described for TemporalAverage.
+func NewTemporalDenoiser(radius, threshold int) (*TemporalDenoiser, error) {
+	if radius < 0 || threshold < 0 {
+		return nil, fmt.Errorf("denoise radius %d and threshold %d must not be negative", radius, threshold)
+	}
+	return &TemporalDenoiser{radius: radius, threshold: threshold}, nil
+}
+
+// Push adds the next input frame and returns the next denoised frame, or nil if the frames
+// following it have not been pushed yet.
+func (d *TemporalDenoiser) Push(f *Frame) (*Frame, error) {
+	if len(d.window) > 0 {
+		err := checkComparable(d.window[0], f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	d.window = append(d.window, f)
+	if len(d.window) > 2*d.radius+1 {
+		d.window = d.window[1:]
+		d.center--
+	}
+	if d.center+d.radius >= len(d.window) {
+		return nil, nil
+	}
+	return d.average()
+}
+
+// Flush returns the denoised frames that are still due after the last input frame.
+func (d *TemporalDenoiser) Flush() ([]*Frame, error) {
+	var frames []*Frame
+	for d.center < len(d.window) {
+		f, err := d.average()
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
+
+// average returns the denoised frame at the centre of the window and advances the centre.
+func (d *TemporalDenoiser) average() (*Frame, error) {
+	lo := maxInt(d.center-d.radius, 0)
+	hi := minInt(d.center+d.radius+1, len(d.window))
+	f, err := TemporalAverage(d.window[lo:hi], d.center-lo, d.threshold)
+	d.center++
+	return f, err
+}
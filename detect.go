@@ -0,0 +1,200 @@
+package y4m
+
+import "fmt"
+
+// FrameRange is the range of frames numbered Start up to but not including End, counting from
+// zero.
+type FrameRange struct {
+	Start int
+	End   int
+}
+
+// Len returns the number of frames in the range.
+func (r FrameRange) Len() int {
+	return r.End - r.Start
+}
+
+func (r FrameRange) String() string {
+	return fmt.Sprintf("[%d, %d)", r.Start, r.End)
+}
+
+// runDetector collects the runs of consecutive frames, counting from zero, that satisfy a
+// condition and are at least minLength frames long.
+type runDetector struct {
+	minLength int
+	n         int // number of frames seen
+	start     int // first frame of the current run, or -1 outside a run
+	ranges    []FrameRange
+}
+
+func newRunDetector(minLength int) runDetector {
+	return runDetector{minLength: maxInt(minLength, 1), start: -1}
+}
+
+// next records whether the next frame satisfies the condition.
+func (d *runDetector) next(in bool) {
+	switch {
+	case in && d.start < 0:
+		d.start = d.n
+	case !in && d.start >= 0:
+		d.end()
+	}
+	d.n++
+}
+
+// end closes the current run at the last frame seen.
+func (d *runDetector) end() {
+	if d.start >= 0 && d.n-d.start >= d.minLength {
+		d.ranges = append(d.ranges, FrameRange{d.start, d.n})
+	}
+	d.start = -1
+}
+
+// found returns the runs found so far, including a run that continues to the last frame seen.
+func (d *runDetector) found() []FrameRange {
+	r := d.ranges
+	if d.start >= 0 && d.n-d.start >= d.minLength {
+		r = append(r[:len(r):len(r)], FrameRange{d.start, d.n})
+	}
+	return r
+}
+
+// BlackDetector finds runs of black frames in a sequence of frames, such as the gaps between
+// programmes or the failed output of a decoder. A frame is black if its mean luma is at most a
+// threshold. Frames are given to Push in order.
+type BlackDetector struct {
+	threshold float64
+	runs      runDetector
+}
+
+// NewBlackDetector returns a BlackDetector that treats frames with mean luma at most threshold
+// as black, such as 20 for limited range content, and reports runs of at least minLength black
+// frames.
+func NewBlackDetector(threshold float64, minLength int) *BlackDetector {
+	return &BlackDetector{threshold: threshold, runs: newRunDetector(minLength)}
+}
+
+// Push examines the next frame.
+func (d *BlackDetector) Push(f *Frame) {
+	d.runs.next(MeanLuma(f) <= d.threshold)
+}
+
+// Ranges returns the runs of black frames found so far.
+func (d *BlackDetector) Ranges() []FrameRange {
+	return d.runs.found()
+}
+
+// MeanLuma returns the mean of the luma samples of frame f.
+func MeanLuma(f *Frame) float64 {
+	var sum int
+	for _, v := range f.Y {
+		sum += int(v)
+	}
+	return float64(sum) / float64(len(f.Y))
+}
+
+// FreezeDetector finds runs of frozen video in a sequence of frames: frames that repeat the
+// frame before them, exactly or to within a tolerance, as when a source stalls or a decoder
+// conceals lost data by repeating a picture. A run starts with the frame that is repeated.
+// Frames are given to Push in order.
+type FreezeDetector struct {
+	tolerance float64
+	prev      *Frame
+	runs      runDetector
+}
+
+// NewFreezeDetector returns a FreezeDetector that treats a frame as a repeat of the frame
+// before it if the mean absolute difference of their luma samples is at most tolerance, and
+// reports runs of at least minLength frozen frames. A tolerance of zero finds identical frames
+// only; a small tolerance also catches repeats that have been re-encoded.
+func NewFreezeDetector(tolerance float64, minLength int) *FreezeDetector {
+	return &FreezeDetector{tolerance: tolerance, runs: newRunDetector(minLength)}
+}
+
+// Push examines the next frame. The frames must all have the same geometry and chroma format.
+func (d *FreezeDetector) Push(f *Frame) error {
+	prev := d.prev
+	d.prev = f
+	if prev == nil {
+		d.runs.next(false)
+		return nil
+	}
+	err := checkComparable(prev, f)
+	if err != nil {
+		return err
+	}
+	var sum int
+	for k, v := range f.Y {
+		sum += absInt(int(v) - int(prev.Y[k]))
+	}
+	frozen := float64(sum)/float64(len(f.Y)) <= d.tolerance
+	if frozen && d.runs.start < 0 {
+		// A run of repeats includes the frame they repeat
+		d.runs.start = d.runs.n - 1
+	}
+	d.runs.next(frozen)
+	return nil
+}
+
+// Ranges returns the runs of frozen frames found so far.
+func (d *FreezeDetector) Ranges() []FrameRange {
+	return d.runs.found()
+}
+
+// SceneDetector finds the shots of a sequence of frames by detecting scene changes: cuts
+// between consecutive frames whose luma histograms differ by more than a threshold. Comparing
+// histograms rather than samples ignores motion within a shot. Frames are given to Push in
+// order.
+type SceneDetector struct {
+	threshold float64
+	prev      *[64]int
+	n         int   // number of frames seen
+	cuts      []int // first frame of each shot after the first
+}
+
+// NewSceneDetector returns a SceneDetector that reports a scene change where the luma
+// histograms of consecutive frames differ by more than threshold. The difference is the
+// fraction of samples that would have to move between histogram bins, from 0 for identical
+// histograms to 1 for disjoint ones; 0.3 finds most cuts without splitting shots on motion.
+func NewSceneDetector(threshold float64) *SceneDetector {
+	return &SceneDetector{threshold: threshold}
+}
+
+// Push examines the next frame and reports whether it starts a new shot. The first frame
+// starts the first shot.
+func (d *SceneDetector) Push(f *Frame) bool {
+	var h [64]int
+	for _, v := range f.Y {
+		h[v>>2]++
+	}
+	prev := d.prev
+	d.prev = &h
+	d.n++
+	if prev == nil {
+		return true
+	}
+	var diff, total int
+	for k := range h {
+		diff += absInt(h[k] - prev[k])
+		total += h[k] + prev[k]
+	}
+	if float64(diff)/float64(total) <= d.threshold {
+		return false
+	}
+	d.cuts = append(d.cuts, d.n-1)
+	return true
+}
+
+// Scenes returns the shots found so far, the last of which ends with the last frame seen.
+func (d *SceneDetector) Scenes() []FrameRange {
+	if d.n == 0 {
+		return nil
+	}
+	var scenes []FrameRange
+	start := 0
+	for _, c := range d.cuts {
+		scenes = append(scenes, FrameRange{start, c})
+		start = c
+	}
+	return append(scenes, FrameRange{start, d.n})
+}
@@ -0,0 +1,125 @@
+package y4m
+
+import (
+	"fmt"
+	"io"
+)
+
+// PlaneDiff summarizes the differences between corresponding planes of two frames.
+type PlaneDiff struct {
+	Differing int // number of samples that differ
+	MaxAbs    int // largest absolute difference between corresponding samples
+}
+
+// Diff compares frames f and g plane by plane. The result is indexed by PlaneY, PlaneCb,
+// PlaneCr and PlaneAlpha. The frames must have the same geometry and chroma format.
+func (f *Frame) Diff(g *Frame) ([4]PlaneDiff, error) {
+	var d [4]PlaneDiff
+	err := checkComparable(f, g)
+	if err != nil {
+		return d, err
+	}
+	for i, p := range [][2][]byte{{f.Y, g.Y}, {f.Cb, g.Cb}, {f.Cr, g.Cr}, {f.Alpha, g.Alpha}} {
+		a, b := p[0], p[1]
+		if len(a) != len(b) {
+			return d, fmt.Errorf("plane %d sizes differ: %d and %d octets", i, len(a), len(b))
+		}
+		for k := range a {
+			e := absInt(int(a[k]) - int(b[k]))
+			if e > 0 {
+				d[i].Differing++
+				d[i].MaxAbs = maxInt(d[i].MaxAbs, e)
+			}
+		}
+	}
+	return d, nil
+}
+
+// FirstDifference returns the plane, PlaneY, PlaneCb, PlaneCr or PlaneAlpha, and the position
+// within it of the first sample that differs between frames f and g, taking the planes in
+// that order and their samples in raster order. found is false if the planes are identical.
+// The frames must have the same geometry and chroma format.
+func (f *Frame) FirstDifference(g *Frame) (plane, x, y int, found bool, err error) {
+	err = checkComparable(f, g)
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+	for i := PlaneY; i <= PlaneAlpha; i++ {
+		a, b := f.Plane(i), g.Plane(i)
+		for y := 0; y < a.Height; y++ {
+			ra, rb := a.Row(y), b.Row(y)
+			for x := range ra {
+				if ra[x] != rb[x] {
+					return i, x, y, true, nil
+				}
+			}
+		}
+	}
+	return 0, 0, 0, false, nil
+}
+
+// StreamDifference locates the first difference between two streams, as found by
+// CompareStreams.
+type StreamDifference struct {
+	// Frame is the index of the first differing frame, counting from zero, or -1 if the
+	// stream headers differ.
+	Frame int
+	// Plane is the plane of the first differing sample, or -1 if the difference is not in the
+	// samples: the stream or frame headers differ, or one stream ends before the other.
+	Plane int
+	X, Y  int  // position of the first differing sample within its plane
+	A, B  byte // values of the first differing sample in each stream
+	// Reason describes the difference.
+	Reason string
+}
+
+func (d *StreamDifference) String() string {
+	if d.Frame < 0 {
+		return d.Reason
+	}
+	return fmt.Sprintf("frame %d: %s", d.Frame, d.Reason)
+}
+
+// CompareStreams reads streams a and b from their current positions to the end and reports
+// whether they are bit-exact copies of each other, comparing the stream headers, the frame
+// headers and every sample. If they are not, the first difference is returned; otherwise the
+// difference is nil. Streams that have just been opened are compared from their first frames.
+// This is the check that regression tests of encoders and filters need.
+func CompareStreams(a, b *Stream) (*StreamDifference, error) {
+	if string(a.Header()) != string(b.Header()) {
+		return &StreamDifference{Frame: -1, Plane: -1,
+			Reason: fmt.Sprintf("stream headers differ: %q, %q", a.Header(), b.Header())}, nil
+	}
+	for n := 0; ; n++ {
+		fa, errA := a.ParseFrame()
+		fb, errB := b.ParseFrame()
+		switch {
+		case errA == io.EOF && errB == io.EOF:
+			return nil, nil
+		case errA == io.EOF || errB == io.EOF:
+			which := "first"
+			if errB == io.EOF {
+				which = "second"
+			}
+			return &StreamDifference{Frame: n, Plane: -1,
+				Reason: fmt.Sprintf("%s stream ends before this frame", which)}, nil
+		case errA != nil:
+			return nil, errA
+		case errB != nil:
+			return nil, errB
+		}
+		if ha, hb := fa.Header.Bytes(), fb.Header.Bytes(); string(ha) != string(hb) {
+			return &StreamDifference{Frame: n, Plane: -1,
+				Reason: fmt.Sprintf("frame headers differ: %q, %q", ha, hb)}, nil
+		}
+		i, x, y, found, err := fa.FirstDifference(fb)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			va, vb := fa.Plane(i).At(x, y), fb.Plane(i).At(x, y)
+			return &StreamDifference{Frame: n, Plane: i, X: x, Y: y, A: va, B: vb,
+				Reason: fmt.Sprintf("%s sample at (%d, %d) differs: %d, %d", PlaneName(i), x, y, va, vb)}, nil
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package y4m
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrTruncatedFrame occurs when the stream ends part way through a frame.
+	ErrTruncatedFrame = errors.New("truncated frame")
+	// ErrBadFrameHeader occurs when a frame header is malformed.
+	ErrBadFrameHeader = errors.New("malformed frame header")
+	// ErrUnsupportedChroma occurs when a stream or frame uses an unknown chroma format.
+	ErrUnsupportedChroma = errors.New("unsupported chroma format")
+	// ErrNotSeekable occurs when a stream read from a pipe or other source that cannot seek,
+	// such as one opened with OpenReader, is asked to seek.
+	ErrNotSeekable = errors.New("stream is not seekable")
+	// ErrLimitExceeded occurs when a header exceeds the Limits of a stream.
+	ErrLimitExceeded = errors.New("header exceeds limit")
+	// ErrBadGeometry occurs when the width or height of a stream or frame is not positive, or
+	// so large that the size of its planes cannot be represented.
+	ErrBadGeometry = errors.New("invalid frame geometry")
+	// ErrUnknownFrameRate occurs when a time is computed for a stream whose frame rate is
+	// unknown, with a zero numerator or denominator.
+	ErrUnknownFrameRate = errors.New("unknown frame rate")
+)
+
+// FrameError records an error encountered while reading a frame, along with the position of
+// the frame in the stream. Use errors.Is to test for the underlying sentinel error.
+type FrameError struct {
+	Frame  int   // frame number, counting from zero
+	Offset int64 // byte offset of the start of the frame header
+	Err    error
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("frame %d at offset %d: %v", e.Frame, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// frameError wraps err in a FrameError for the next frame, which begins at offset. An io.EOF
+// at the start of a frame marks the clean end of the stream and is returned unwrapped.
+func (s *Stream) frameError(offset int64, err error) error {
+	return newFrameError(s.frameIndex, offset, err)
+}
+
+func newFrameError(frame int, offset int64, err error) error {
+	if err == io.EOF {
+		return err
+	}
+	if err == io.ErrUnexpectedEOF {
+		err = ErrTruncatedFrame
+	}
+	return &FrameError{Frame: frame, Offset: offset, Err: err}
+}
@@ -0,0 +1,102 @@
+package y4m
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// FFmpegOptions configures OpenAnyVideoWith.
+type FFmpegOptions struct {
+	// Binary is the path of the ffmpeg command line tool. If empty, ffmpeg is looked up in the
+	// PATH.
+	Binary string
+	// PixelFormat is the ffmpeg pixel format of the decoded frames, such as "yuv420p" or
+	// "yuv444p". Sources in formats that y4m cannot carry, such as 10-bit video, must be
+	// converted to one that it can. If empty, ffmpeg chooses a format close to the source's.
+	PixelFormat string
+}
+
+// ffmpegReader reads the standard output of an ffmpeg process, and reports the failure of the
+// process in place of the end of its output.
+type ffmpegReader struct {
+	stdout io.ReadCloser
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	done   bool
+	err    error // result of the process, once done
+}
+
+func (r *ffmpegReader) Read(b []byte) (int, error) {
+	if r.done {
+		return 0, r.err
+	}
+	n, err := r.stdout.Read(b)
+	if err == io.EOF {
+		r.done = true
+		r.err = io.EOF
+		err = r.cmd.Wait()
+		if err != nil {
+			r.err = commandError("ffmpeg", err, r.stderr.Bytes())
+		}
+		return n, r.err
+	}
+	return n, err
+}
+
+// Close stops the process if it is still running, or else returns its failure.
+func (r *ffmpegReader) Close() error {
+	if r.done && r.err != io.EOF {
+		return r.err
+	} else if r.done {
+		return nil
+	}
+	r.done = true
+	r.err = io.EOF
+	// Closing the pipe first stops any process still writing to it
+	r.stdout.Close()
+	r.cmd.Process.Kill()
+	r.cmd.Wait()
+	return nil
+}
+
+// OpenAnyVideo decodes the named video file, in any format that ffmpeg reads, such as MP4 or
+// MKV, by running ffmpeg and reading its y4m output. As with OpenReader, the frames can only be
+// read in order. If ffmpeg fails, its error, including its messages, is returned in place of
+// the end of the stream, wrapped in a FrameError if it fails part way through a frame. Close
+// stops ffmpeg if the stream has not been read to the end.
+func OpenAnyVideo(name string) (*Stream, error) {
+	return OpenAnyVideoWith(name, FFmpegOptions{})
+}
+
+// OpenAnyVideoWith is like OpenAnyVideo, with options.
+func OpenAnyVideoWith(name string, o FFmpegOptions) (*Stream, error) {
+	binary := o.Binary
+	if binary == "" {
+		binary = "ffmpeg"
+	}
+	args := []string{"-nostdin", "-loglevel", "error", "-i", name}
+	if o.PixelFormat != "" {
+		args = append(args, "-pix_fmt", o.PixelFormat)
+	}
+	args = append(args, "-f", "yuv4mpegpipe", "-")
+	r := &ffmpegReader{cmd: exec.Command(binary, args...)}
+	r.cmd.Stderr = &r.stderr
+	var err error
+	r.stdout, err = r.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = r.cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("cannot run ffmpeg: %w", err)
+	}
+	s, err := OpenReader(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	s.closer = r
+	return s, nil
+}
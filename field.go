@@ -0,0 +1,128 @@
+package y4m
+
+import (
+	"fmt"
+)
+
+// Fields returns the top and bottom fields of the frame as frames of half the height. The top
+// field holds the even lines of each plane and the bottom field the odd lines. Each field
+// carries a copy of the frame's header. The frame height must be a multiple of twice the
+// vertical chroma subsampling factor, so that each field has whole chroma lines.
+func (f *Frame) Fields() (top, bottom *Frame, err error) {
+	_, yss, err := subsampling(f.Chroma)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.Height%(2*yss) != 0 {
+		return nil, nil, fmt.Errorf("cannot split %s frame of height %d into fields; height must be a multiple of %d",
+			f.Chroma, f.Height, 2*yss)
+	}
+	fields := [2]*Frame{}
+	for parity := range fields {
+		g := &Frame{Header: f.Header.Copy(), Width: f.Width, Height: f.Height / 2, Chroma: f.Chroma}
+		g.Y = fieldLines(f.Plane(PlaneY), parity)
+		g.Cb = fieldLines(f.Plane(PlaneCb), parity)
+		g.Cr = fieldLines(f.Plane(PlaneCr), parity)
+		g.Alpha = fieldLines(f.Plane(PlaneAlpha), parity)
+		fields[parity] = g
+	}
+	return fields[0], fields[1], nil
+}
+
+// TopFieldFirst reports whether the top field of frame f, read from the stream, is displayed
+// first. The frame's I field is used when it has one; otherwise the stream's interlacing mode
+// decides. Progressive and unknown content is treated as top field first.
+func (s *Stream) TopFieldFirst(f *Frame) bool {
+	if f.Header != nil && f.Header.I != nil {
+		return f.Header.I.TopFieldFirst()
+	}
+	return s.Interlacing != "b"
+}
+
+// fieldAlignment returns the multiple of lines by which the frames of a format with vertical
+// chroma subsampling factor yss can be shifted or cut vertically. When the chroma is
+// subsampled vertically and the frames are interlaced, the chroma lines alternate between the
+// fields like the luma lines, so they must be shifted by an even number of chroma lines.
+func fieldAlignment(yss int, interlaced bool) int {
+	if interlaced && yss > 1 {
+		return 2 * yss
+	}
+	return yss
+}
+
+// frameI returns the frame's I field, or if it has none, implied, the I field implied by the
+// interlacing mode of its stream, which may be nil.
+func (f *Frame) frameI(implied *IField) *IField {
+	if f.Header != nil && f.Header.I != nil {
+		return f.Header.I
+	}
+	return implied
+}
+
+// Interlaced reports whether the interlacing mode of the stream is top field first, bottom
+// field first or mixed, in which case its frames may be sampled as two fields.
+func (s *Stream) Interlaced() bool {
+	return s.Interlacing == "t" || s.Interlacing == "b" || s.Interlacing == "m"
+}
+
+// CropGrid returns the multiples to which the horizontal and vertical offsets and sizes of a
+// crop of the stream's frames must be aligned: the chroma subsampling factors, with the
+// vertical factor doubled for interlaced streams with vertically subsampled chroma, as
+// described for Frame.Crop.
+func (s *Stream) CropGrid() (x, y int) {
+	xss, yss, err := subsampling(s.Chroma)
+	if err != nil {
+		return 1, 1
+	}
+	return xss, fieldAlignment(yss, s.Interlaced())
+}
+
+// fieldLines returns the packed lines of plane p whose line number has the given parity, 0
+// for even and 1 for odd. An absent plane gives nil.
+func fieldLines(p Plane, parity int) []byte {
+	if p.Data == nil {
+		return nil
+	}
+	out := make([]byte, 0, p.Width*p.Height/2)
+	for y := parity; y < p.Height; y += 2 {
+		out = append(out, p.Row(y)...)
+	}
+	return out
+}
+
+// WeaveFields reassembles a frame from its top and bottom fields, as returned by Fields. The
+// fields must have the same geometry and chroma format. The frame takes a copy of the top
+// field's header.
+func WeaveFields(top, bottom *Frame) (*Frame, error) {
+	if top.Width != bottom.Width || top.Height != bottom.Height || top.Chroma != bottom.Chroma {
+		return nil, fmt.Errorf("cannot weave %dx%d %s field with %dx%d %s field",
+			top.Width, top.Height, top.Chroma, bottom.Width, bottom.Height, bottom.Chroma)
+	}
+	f := &Frame{Header: top.Header.Copy(), Width: top.Width, Height: 2 * top.Height, Chroma: top.Chroma}
+	err := checkGeometry(f.Width, f.Height, f.Chroma)
+	if err != nil {
+		return nil, err
+	}
+	f.Y = interleaveLines(top.Plane(PlaneY), bottom.Plane(PlaneY))
+	f.Cb = interleaveLines(top.Plane(PlaneCb), bottom.Plane(PlaneCb))
+	f.Cr = interleaveLines(top.Plane(PlaneCr), bottom.Plane(PlaneCr))
+	f.Alpha = interleaveLines(top.Plane(PlaneAlpha), bottom.Plane(PlaneAlpha))
+	if f.Header == nil {
+		f.Header = &FrameHeader{MagicString: "FRAME"}
+	}
+	return f, nil
+}
+
+// interleaveLines returns a packed plane of twice the height of fields t and b, taking even
+// lines from t and odd lines from b. Absent planes give nil.
+func interleaveLines(t, b Plane) []byte {
+	if t.Data == nil || b.Data == nil {
+		return nil
+	}
+	out := make([]byte, 0, 2*t.Width*t.Height)
+	for y := 0; y < t.Height; y++ {
+		out = append(out, t.Row(y)...)
+		out = append(out, b.Row(y)...)
+	}
+	return out
+}
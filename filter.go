@@ -0,0 +1,152 @@
+package y4m
+
+import (
+	"fmt"
+	"math"
+)
+
+// GaussianBlur blurs the luma and chroma planes of the frame with a Gaussian of standard
+// deviation sigma, measured in luma samples, so that subsampled chroma planes are blurred by
+// the same amount of the picture. A sigma of zero leaves the frame unchanged. The alpha plane
+// is not blurred.
+func (f *Frame) GaussianBlur(sigma float64) error {
+	if sigma < 0 {
+		return fmt.Errorf("blur sigma %g is negative", sigma)
+	}
+	if sigma == 0 {
+		return nil
+	}
+	f.Y = blurPlane(f.Plane(PlaneY), sigma, sigma)
+	if len(f.Cb) > 0 {
+		xss, yss := float64(xSubsamplingFactor[f.Chroma]), float64(ySubsamplingFactor[f.Chroma])
+		f.Cb = blurPlane(f.Plane(PlaneCb), sigma/xss, sigma/yss)
+		f.Cr = blurPlane(f.Plane(PlaneCr), sigma/xss, sigma/yss)
+	}
+	return nil
+}
+
+// UnsharpMask sharpens the luma plane of the frame by adding amount times the difference
+// between the plane and a copy blurred with a Gaussian of standard deviation sigma. Differences
+// of at most threshold are left alone, so that flat areas and their noise are not amplified.
+// Chroma and alpha planes are unchanged, as sharpening chroma mostly adds color fringes.
+func (f *Frame) UnsharpMask(sigma, amount float64, threshold int) error {
+	if sigma < 0 || amount < 0 || threshold < 0 {
+		return fmt.Errorf("unsharp mask sigma %g, amount %g and threshold %d must not be negative",
+			sigma, amount, threshold)
+	}
+	if sigma == 0 || amount == 0 {
+		return nil
+	}
+	blurred := blurPlane(f.Plane(PlaneY), sigma, sigma)
+	for k, v := range f.Y {
+		d := int(v) - int(blurred[k])
+		if absInt(d) > threshold {
+			f.Y[k] = clampByte(int(v) + int(math.Round(amount*float64(d))))
+		}
+	}
+	return nil
+}
+
+// Median replaces each luma and chroma sample of the frame by the median of the samples in the
+// square of side 2*radius+1 centred on it, which removes impulse noise such as dropouts and
+// dead pixels while keeping edges. The radius applies to each plane in its own samples. Samples
+// beyond the edges of a plane repeat the edge samples. The alpha plane is unchanged.
+func (f *Frame) Median(radius int) error {
+	if radius < 0 {
+		return fmt.Errorf("median radius %d is negative", radius)
+	}
+	if radius == 0 {
+		return nil
+	}
+	f.Y = medianPlane(f.Plane(PlaneY), radius)
+	if len(f.Cb) > 0 {
+		f.Cb = medianPlane(f.Plane(PlaneCb), radius)
+		f.Cr = medianPlane(f.Plane(PlaneCr), radius)
+	}
+	return nil
+}
+
+// gaussianKernel returns the normalized weights of a Gaussian of standard deviation sigma
+// from -r to r, where r is about three standard deviations.
+func gaussianKernel(sigma float64) []float32 {
+	r := int(math.Ceil(3 * sigma))
+	ws := make([]float64, 2*r+1)
+	var sum float64
+	for i := range ws {
+		x := float64(i - r)
+		ws[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += ws[i]
+	}
+	k := make([]float32, len(ws))
+	for i, v := range ws {
+		k[i] = float32(v / sum)
+	}
+	return k
+}
+
+// blurPlane returns plane p blurred with Gaussians of standard deviations sx horizontally and
+// sy vertically. Source samples beyond the edges of the plane repeat the edge samples.
+func blurPlane(p Plane, sx, sy float64) []byte {
+	kx, ky := gaussianKernel(sx), gaussianKernel(sy)
+	rx, ry := len(kx)/2, len(ky)/2
+	w, h := p.Width, p.Height
+	clamp := func(i, n int) int { return maxInt(0, minInt(i, n-1)) }
+	// Filter rows, then columns
+	tmp := make([]float32, w*h)
+	for y := 0; y < h; y++ {
+		row := p.Row(y)
+		for x := 0; x < w; x++ {
+			var v float32
+			for j, wt := range kx {
+				v += wt * float32(row[clamp(x+j-rx, w)])
+			}
+			tmp[y*w+x] = v
+		}
+	}
+	out := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float32
+			for j, wt := range ky {
+				v += wt * tmp[clamp(y+j-ry, h)*w+x]
+			}
+			out[y*w+x] = clampByte(int(v + 0.5))
+		}
+	}
+	return out
+}
+
+// medianPlane returns plane p median filtered over squares of side 2*r+1. A histogram of the
+// window is updated as it slides along each row, so the cost per sample grows with r rather
+// than with the area of the window.
+func medianPlane(p Plane, r int) []byte {
+	w, h := p.Width, p.Height
+	n := (2*r + 1) * (2*r + 1)
+	clamp := func(i, n int) int { return maxInt(0, minInt(i, n-1)) }
+	out := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		var hist [256]int
+		column := func(x, delta int) {
+			x = clamp(x, w)
+			for j := y - r; j <= y+r; j++ {
+				hist[p.At(x, clamp(j, h))] += delta
+			}
+		}
+		for x := -r; x <= r; x++ {
+			column(x, 1)
+		}
+		for x := 0; x < w; x++ {
+			if x > 0 {
+				column(x-r-1, -1)
+				column(x+r, 1)
+			}
+			// The median is the first value at which the cumulative count passes half
+			count, v := 0, 0
+			for ; count+hist[v] <= n/2; v++ {
+				count += hist[v]
+			}
+			out[y*w+x] = byte(v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,200 @@
+package y4m
+
+import (
+	"fmt"
+	"math"
+)
+
+// NewFrame creates a frame of width w and height h in the given chroma format, with planes
+// allocated to the sizes the format requires. Luma samples are zero, chroma samples are
+// neutral (128), and alpha samples, if present, are opaque. The frame header is a bare
+// "FRAME" header.
+func NewFrame(w, h int, chroma string) (*Frame, error) {
+	err := checkSize(w, h)
+	if err != nil {
+		return nil, err
+	}
+	_, _, err = subsampling(chroma)
+	if err != nil {
+		return nil, err
+	}
+	luma, chromaSize, alpha := planeSizes(w, h, chroma)
+	f := &Frame{
+		Header: &FrameHeader{MagicString: "FRAME"},
+		Width:  w,
+		Height: h,
+		Chroma: chroma,
+		Y:      make([]byte, luma),
+	}
+	if chromaSize > 0 {
+		f.Cb = make([]byte, chromaSize)
+		f.Cr = make([]byte, chromaSize)
+		for k := range f.Cb {
+			f.Cb[k] = 0x80
+			f.Cr[k] = 0x80
+		}
+	}
+	if alpha > 0 {
+		f.Alpha = make([]byte, alpha)
+		for k := range f.Alpha {
+			f.Alpha[k] = 0xff
+		}
+	}
+	return f, nil
+}
+
+// maxDimension is the largest width or height accepted, which keeps the plane size arithmetic
+// well within the range of int64.
+const maxDimension = 1 << 20
+
+// checkSize checks that the dimensions of a frame of width w and height h are positive and at
+// most maxDimension, and that its planes can be indexed by an int.
+func checkSize(w, h int) error {
+	if w < 1 || h < 1 || w > maxDimension || h > maxDimension || int64(w)*int64(h) > math.MaxInt {
+		return fmt.Errorf("%w: frame dimensions %dx%d out of range", ErrBadGeometry, w, h)
+	}
+	return nil
+}
+
+// planeSizes returns the sizes in octets of the luma plane, each chroma plane, and the alpha
+// plane of a frame of width w and height h in the given chroma format.
+func planeSizes(w, h int, chroma string) (luma, chromaSize, alpha int) {
+	luma = w * h
+	cw, ch := chromaDims(w, h, chroma)
+	chromaSize = cw * ch
+	if chroma == "444alpha" {
+		alpha = w * h
+	}
+	return luma, chromaSize, alpha
+}
+
+// chromaDims returns the width and height of the chroma planes of a frame of width w and
+// height h in the given chroma format, or zero for formats without chroma planes. When w or h
+// is not a multiple of the subsampling factor, the last chroma column or row covers the
+// luma samples that remain, as FFmpeg and libvpx write odd sized 4:2:0 frames.
+func chromaDims(w, h int, chroma string) (cw, ch int) {
+	xss, yss := xSubsamplingFactor[chroma], ySubsamplingFactor[chroma]
+	if chroma == "mono" || xss == 0 || yss == 0 {
+		return 0, 0
+	}
+	return (w + xss - 1) / xss, (h + yss - 1) / yss
+}
+
+// checkGeometry checks that a frame of width w and height h can be represented in the given
+// chroma format with whole chroma samples, as required by operations that place or combine
+// chroma blocks, such as Resize, Stack and WeaveFields.
+func checkGeometry(w, h int, chroma string) error {
+	err := checkSize(w, h)
+	if err != nil {
+		return err
+	}
+	if chroma == "mono" {
+		return nil
+	}
+	xss, ok := xSubsamplingFactor[chroma]
+	if !ok {
+		return fmt.Errorf("unsupported chroma format %q", chroma)
+	}
+	yss := ySubsamplingFactor[chroma]
+	if w%xss != 0 || h%yss != 0 {
+		return fmt.Errorf("dimensions %dx%d are not a multiple of %s chroma subsampling", w, h, chroma)
+	}
+	return nil
+}
+
+// Copy returns a deep copy of the frame, including its header. Copying a frame parsed from a
+// memory mapped stream gives it planes that can be modified.
+func (f *Frame) Copy() *Frame {
+	g := &Frame{
+		Width:  f.Width,
+		Height: f.Height,
+		Chroma: f.Chroma,
+		Y:      copyBytes(f.Y),
+		Cb:     copyBytes(f.Cb),
+		Cr:     copyBytes(f.Cr),
+		Alpha:  copyBytes(f.Alpha),
+	}
+	g.Header = f.Header.Copy()
+	return g
+}
+
+// Copy returns a deep copy of the frame header. The copy of a nil header is nil.
+func (h *FrameHeader) Copy() *FrameHeader {
+	if h == nil {
+		return nil
+	}
+	g := *h
+	if h.I != nil {
+		i := *h.I
+		g.I = &i
+	}
+	g.Metadata = append([]string(nil), h.Metadata...)
+	g.Raw = copyBytes(h.Raw)
+	return &g
+}
+
+// Equal reports whether frames f and g have the same geometry, chroma format, plane data,
+// alpha, and frame header I field and metadata.
+func (f *Frame) Equal(g *Frame) bool {
+	return f.EqualWithTolerance(g, 0)
+}
+
+// EqualWithTolerance is like Equal, but allows corresponding samples of f and g to differ by
+// up to tol.
+func (f *Frame) EqualWithTolerance(g *Frame, tol int) bool {
+	if f.Width != g.Width || f.Height != g.Height || f.Chroma != g.Chroma {
+		return false
+	}
+	if !planesWithin(f.Y, g.Y, tol) || !planesWithin(f.Cb, g.Cb, tol) ||
+		!planesWithin(f.Cr, g.Cr, tol) || !planesWithin(f.Alpha, g.Alpha, tol) {
+		return false
+	}
+	return headersEqual(f.Header, g.Header)
+}
+
+// headersEqual reports whether frame headers a and b have the same I field and metadata. A
+// nil header is equal to a header with neither.
+func headersEqual(a, b *FrameHeader) bool {
+	var ai, bi *IField
+	var am, bm []string
+	if a != nil {
+		ai, am = a.I, a.Metadata
+	}
+	if b != nil {
+		bi, bm = b.I, b.Metadata
+	}
+	if (ai == nil) != (bi == nil) || (ai != nil && *ai != *bi) {
+		return false
+	}
+	if len(am) != len(bm) {
+		return false
+	}
+	for k := range am {
+		if am[k] != bm[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// planesWithin reports whether planes a and b have equal length and no corresponding samples
+// differ by more than tol.
+func planesWithin(a, b []byte, tol int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		d := int(a[k]) - int(b[k])
+		if d > tol || -d > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,67 @@
+package y4m
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// FuzzParseHeader checks that stream headers are parsed without panicking, and that a parsed
+// header written back with Header parses to the same fields.
+func FuzzParseHeader(f *testing.F) {
+	f.Add([]byte("YUV4MPEG2 W1920 H1080 F25:1 Ip A1:1 C420jpeg\n"))
+	f.Add([]byte("YUV4MPEG2 W720 H480 F30000:1001 It A10:11 C422 XYSCSS=422 XCOLORRANGE=LIMITED\n"))
+	f.Add([]byte("YUV4MPEG2 W7 H5 Im C444alpha XFOO=bar\n"))
+	f.Add([]byte("YUV4MPEG2 W16 H8 Cmono"))
+	f.Add([]byte("YUV4MPEG2 W1920 H10"))
+	f.Add([]byte("YUV4MPEG2 W4 H4 F25:1 I C420jpeg\n"))
+	f.Add([]byte("YUV4MPEG2 W0 H-1 F1:0 A:\n"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s, err := OpenReader(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		r, err := OpenReader(bytes.NewReader(s.Header()))
+		if err != nil {
+			t.Fatalf("header %q written as %q does not parse: %v", b, s.Header(), err)
+		}
+		if s.Width != r.Width || s.Height != r.Height || s.Chroma != r.Chroma ||
+			s.Interlacing != r.Interlacing || *s.FrameRate != *r.FrameRate ||
+			*s.SampleAspectRatio != *r.SampleAspectRatio || !reflect.DeepEqual(s.Metadata, r.Metadata) {
+			t.Fatalf("header %q written as %q parses differently", b, s.Header())
+		}
+	})
+}
+
+// FuzzParseFrameHeader checks that frame headers are parsed without panicking, both alone and
+// followed by frame data in a stream, and that a parsed header written back with Bytes parses
+// to the same fields.
+func FuzzParseFrameHeader(f *testing.F) {
+	f.Add([]byte("FRAME\n"))
+	f.Add([]byte("FRAME Itpp XFOO=bar\n"))
+	f.Add([]byte("FRAME I1pp XTIMECODE=00:00:00;02\n"))
+	f.Add([]byte("FRAME Ibi"))
+	f.Add([]byte("FRAMX\n"))
+	f.Add([]byte("FRAME Izzz\n"))
+	f.Add([]byte("FRAME\n\x10\x10\x10"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		h, err := parseFrameHeaderBytes(b)
+		if err == nil {
+			g, err := parseFrameHeaderBytes(h.Bytes())
+			if err != nil {
+				t.Fatalf("frame header %q written as %q does not parse: %v", b, h.Bytes(), err)
+			}
+			if !reflect.DeepEqual(h.I, g.I) || !reflect.DeepEqual(h.Metadata, g.Metadata) {
+				t.Fatalf("frame header %q written as %q parses differently", b, h.Bytes())
+			}
+		}
+		stream := append([]byte("YUV4MPEG2 W4 H2 F25:1 Ip C420jpeg\n"), b...)
+		s, err := OpenReader(bytes.NewReader(stream))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for err == nil {
+			_, err = s.ParseFrame()
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package y4m
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Chromaticity is a CIE 1931 xy chromaticity coordinate in units of 0.00002, as in the
+// mastering display colour volume SEI message of HEVC.
+type Chromaticity struct {
+	X int
+	Y int
+}
+
+// MasteringDisplay describes the color volume of the display on which HDR content was mastered,
+// as defined by SMPTE ST 2086. It is carried in the stream header by the XMASTERING_DISPLAY tag.
+type MasteringDisplay struct {
+	Red          Chromaticity
+	Green        Chromaticity
+	Blue         Chromaticity
+	WhitePoint   Chromaticity
+	MaxLuminance int // in units of 0.0001 cd/m²
+	MinLuminance int // in units of 0.0001 cd/m²
+}
+
+const masteringDisplayFormat = "G(%d,%d)B(%d,%d)R(%d,%d)WP(%d,%d)L(%d,%d)"
+
+// String returns the value of the XMASTERING_DISPLAY tag, which has the syntax of the
+// --master-display option of x265, e.g. "G(13250,34500)B(7500,3000)R(34000,16000)
+// WP(15635,16450)L(10000000,1)" without the space.
+func (m *MasteringDisplay) String() string {
+	return fmt.Sprintf(masteringDisplayFormat, m.Green.X, m.Green.Y, m.Blue.X, m.Blue.Y,
+		m.Red.X, m.Red.Y, m.WhitePoint.X, m.WhitePoint.Y, m.MaxLuminance, m.MinLuminance)
+}
+
+// ParseMasteringDisplay parses the value of an XMASTERING_DISPLAY tag.
+func ParseMasteringDisplay(s string) (*MasteringDisplay, error) {
+	m := new(MasteringDisplay)
+	_, err := fmt.Sscanf(s, masteringDisplayFormat, &m.Green.X, &m.Green.Y, &m.Blue.X, &m.Blue.Y,
+		&m.Red.X, &m.Red.Y, &m.WhitePoint.X, &m.WhitePoint.Y, &m.MaxLuminance, &m.MinLuminance)
+	if err != nil || m.String() != s {
+		return nil, fmt.Errorf("could not parse mastering display %q", s)
+	}
+	for _, c := range []Chromaticity{m.Red, m.Green, m.Blue, m.WhitePoint} {
+		if c.X < 0 || c.X > 50000 || c.Y < 0 || c.Y > 50000 {
+			return nil, fmt.Errorf("mastering display %q has a chromaticity outside [0, 50000]", s)
+		}
+	}
+	if m.MinLuminance < 0 || m.MaxLuminance <= m.MinLuminance {
+		return nil, fmt.Errorf("mastering display %q has an invalid luminance range", s)
+	}
+	return m, nil
+}
+
+// parseLightLevel parses the value of an XMAXCLL or XMAXFALL tag, a positive number of cd/m².
+func parseLightLevel(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 || strconv.Itoa(n) != s {
+		return 0, false
+	}
+	return n, true
+}
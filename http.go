@@ -0,0 +1,94 @@
+package y4m
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ContentType is the MIME type of YUV4MPEG2 streams, as used by ffmpeg and browsers.
+const ContentType = "video/x-yuv4mpegpipe"
+
+// ServeStream writes the header and the remaining frames of stream s to an HTTP response,
+// flushing the response after each frame so that the client can decode frames as they
+// arrive. The response has no length and does not support range requests, so it is sent with
+// chunked transfer encoding. An error after the first frame cannot be reported to the client,
+// which sees the response end early.
+func ServeStream(w http.ResponseWriter, s *Stream) error {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Accept-Ranges", "none")
+	var flush func()
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
+	}
+	return copyStream(w, s, flush)
+}
+
+// copyStream writes the header and the remaining frames of stream s to w, calling flush, if
+// not nil, after each frame.
+func copyStream(w io.Writer, s *Stream, flush func()) error {
+	_, err := w.Write(s.Header())
+	if err != nil {
+		return err
+	}
+	for {
+		f, err := s.ParseFrame()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		for _, b := range [][]byte{frameHeaderBytes(f.Header), f.Y, f.Cb, f.Cr, f.Alpha} {
+			_, err = w.Write(b)
+			if err != nil {
+				return err
+			}
+		}
+		if flush != nil {
+			flush()
+		}
+	}
+}
+
+// FileHandler returns an HTTP handler that serves the named stream file with ServeStream,
+// opening it afresh for each GET request.
+func FileHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s, err := Open(name)
+		if err != nil {
+			http.Error(w, "cannot open stream", http.StatusInternalServerError)
+			return
+		}
+		defer s.Close()
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Type", ContentType)
+			return
+		}
+		ServeStream(w, s)
+	})
+}
+
+// OpenURL requests a stream from an HTTP or HTTPS URL with http.Get and parses its header. As
+// with OpenReader, the frames can only be read in order. Close closes the response body.
+func OpenURL(url string) (*Stream, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("cannot get %s: %s", url, resp.Status)
+	}
+	s, err := OpenReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	s.closer = resp.Body
+	return s, nil
+}
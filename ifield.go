@@ -0,0 +1,284 @@
+package y4m
+
+import (
+	"fmt"
+)
+
+// Frame presentation values of the I field, describing how a frame is to be displayed.
+const (
+	// PresentTopFirst marks an interlaced frame whose top field is displayed first.
+	PresentTopFirst byte = 't'
+	// PresentTopFirstRepeat marks a frame displayed as top, bottom, top (soft telecine).
+	PresentTopFirstRepeat byte = 'T'
+	// PresentBottomFirst marks an interlaced frame whose bottom field is displayed first.
+	PresentBottomFirst byte = 'b'
+	// PresentBottomFirstRepeat marks a frame displayed as bottom, top, bottom (soft telecine).
+	PresentBottomFirstRepeat byte = 'B'
+	// PresentSingle marks a progressive frame displayed for one frame period.
+	PresentSingle byte = '1'
+	// PresentDouble marks a progressive frame displayed for two frame periods.
+	PresentDouble byte = '2'
+	// PresentTriple marks a progressive frame displayed for three frame periods.
+	PresentTriple byte = '3'
+)
+
+// Temporal and spatial sampling values of the I field.
+const (
+	// SamplingProgressive indicates the frame was sampled progressively.
+	SamplingProgressive byte = 'p'
+	// SamplingInterlaced indicates the frame was sampled as two fields.
+	SamplingInterlaced byte = 'i'
+	// SamplingUnknown indicates unknown spatial sampling. It is not valid for temporal sampling.
+	SamplingUnknown byte = '?'
+)
+
+// String returns the three character I field value, e.g. "tip".
+func (i *IField) String() string {
+	return string([]byte{i.Presentation, i.Temporal, i.Spatial})
+}
+
+// FieldCount returns the number of field periods for which the frame is displayed: 2 for a
+// regular frame, 3 for a repeat-field frame, and 4 or 6 for doubled and tripled frames.
+func (i *IField) FieldCount() int {
+	switch i.Presentation {
+	case PresentTopFirstRepeat, PresentBottomFirstRepeat:
+		return 3
+	case PresentDouble:
+		return 4
+	case PresentTriple:
+		return 6
+	}
+	return 2
+}
+
+// TopFieldFirst reports whether the frame's top field is displayed first.
+func (i *IField) TopFieldFirst() bool {
+	return i.Presentation != PresentBottomFirst && i.Presentation != PresentBottomFirstRepeat
+}
+
+// swapPresentation returns presentation value p with the top and bottom fields exchanged, as
+// when the lines of a frame are shifted by one.
+func swapPresentation(p byte) byte {
+	switch p {
+	case PresentTopFirst:
+		return PresentBottomFirst
+	case PresentBottomFirst:
+		return PresentTopFirst
+	case PresentTopFirstRepeat:
+		return PresentBottomFirstRepeat
+	case PresentBottomFirstRepeat:
+		return PresentTopFirstRepeat
+	}
+	return p
+}
+
+// checkIField checks the presentation, temporal sampling and spatial sampling values of an I
+// field.
+func checkIField(presentation, temporal, spatial byte) error {
+	switch presentation {
+	case PresentTopFirst, PresentTopFirstRepeat, PresentBottomFirst, PresentBottomFirstRepeat,
+		PresentSingle, PresentDouble, PresentTriple:
+	default:
+		return fmt.Errorf("%w: presentation subfield has unexpected value %c", ErrBadFrameHeader,
+			presentation)
+	}
+	if temporal != SamplingProgressive && temporal != SamplingInterlaced {
+		return fmt.Errorf("%w: temporal sampling subfield has unexpected value %c", ErrBadFrameHeader,
+			temporal)
+	}
+	if spatial != SamplingProgressive && spatial != SamplingInterlaced && spatial != SamplingUnknown {
+		return fmt.Errorf("%w: spatial sampling subfield has unexpected value %c", ErrBadFrameHeader,
+			spatial)
+	}
+	return nil
+}
+
+// SetI sets the I field of the frame header to the given presentation, temporal sampling and
+// spatial sampling values, such as PresentTopFirst, SamplingInterlaced and SamplingInterlaced
+// for a frame of interlaced video with the top field first. The header's raw bytes are
+// regenerated so that the field is written.
+func (h *FrameHeader) SetI(presentation, temporal, spatial byte) error {
+	err := checkIField(presentation, temporal, spatial)
+	if err != nil {
+		return err
+	}
+	h.I = &IField{Presentation: presentation, Temporal: temporal, Spatial: spatial}
+	h.Raw = h.Bytes()
+	return nil
+}
+
+// DeleteI removes the I field from the frame header, which leaves the frame described by the
+// interlacing mode of its stream.
+func (h *FrameHeader) DeleteI() {
+	if h.I != nil {
+		h.I = nil
+		h.Raw = h.Bytes()
+	}
+}
+
+// SetI sets the I field of the frame's header, creating a bare "FRAME" header if the frame has
+// none. See FrameHeader.SetI.
+func (f *Frame) SetI(presentation, temporal, spatial byte) error {
+	if f.Header == nil {
+		f.Header = &FrameHeader{MagicString: "FRAME"}
+	}
+	return f.Header.SetI(presentation, temporal, spatial)
+}
+
+// IField returns the I field implied for every frame of the stream by its interlacing mode:
+// progressive frames displayed once for "p", and interlaced frames with the top or bottom
+// field first for "t" and "b". It returns nil for the mixed ("m") and unknown ("?") modes,
+// which imply nothing about individual frames.
+func (s *Stream) IField() *IField {
+	switch s.Interlacing {
+	case "p":
+		return &IField{Presentation: PresentSingle, Temporal: SamplingProgressive, Spatial: SamplingProgressive}
+	case "t":
+		return &IField{Presentation: PresentTopFirst, Temporal: SamplingInterlaced, Spatial: SamplingInterlaced}
+	case "b":
+		return &IField{Presentation: PresentBottomFirst, Temporal: SamplingInterlaced, Spatial: SamplingInterlaced}
+	}
+	return nil
+}
+
+// checkI checks that a frame written to a mixed-mode stream has the I field that the format
+// requires of every frame of such a stream.
+func (s *Stream) checkI(f *Frame) error {
+	if s.Interlacing == "m" && (f.Header == nil || f.Header.I == nil) {
+		return fmt.Errorf("frame of a mixed-mode (Im) stream has no I field")
+	}
+	return nil
+}
+
+// field identifies the top or bottom field of a frame.
+type field struct {
+	frame *Frame
+	top   bool
+}
+
+// RepeatExpander converts frames carrying repeat presentation values (T, B, 2 and 3) into a
+// sequence in which every frame is displayed for exactly one frame period, which is what
+// players that ignore the I field expect. Repeated fields are woven with the neighbouring
+// frame's opposite field, so telecined content plays back with correct timing.
+type RepeatExpander struct {
+	leftover *field
+}
+
+// Push adds the next frame in display order and returns the frames that are now complete.
+// A frame that is displayed without weaving is returned as is, and may be returned more than
+// once for doubled and tripled frames.
+func (e *RepeatExpander) Push(f *Frame) ([]*Frame, error) {
+	i := &IField{Presentation: PresentSingle, Temporal: SamplingProgressive, Spatial: SamplingProgressive}
+	if f.Header != nil && f.Header.I != nil {
+		i = f.Header.I
+	}
+	top := i.TopFieldFirst()
+	var fields []field
+	if e.leftover != nil {
+		fields = append(fields, *e.leftover)
+		e.leftover = nil
+	}
+	for k := 0; k < i.FieldCount(); k++ {
+		fields = append(fields, field{frame: f, top: top})
+		top = !top
+	}
+	var out []*Frame
+	for len(fields) >= 2 {
+		a, b := fields[0], fields[1]
+		if a.top == b.top {
+			// Fields of the same parity cannot be woven; drop the older one
+			fields = fields[1:]
+			continue
+		}
+		fields = fields[2:]
+		if a.frame == b.frame {
+			out = append(out, normalizePresentation(a.frame, a.top))
+			continue
+		}
+		woven, err := weave(a, b)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, woven)
+	}
+	if len(fields) == 1 {
+		e.leftover = &fields[0]
+	}
+	return out, nil
+}
+
+// normalizePresentation replaces a repeat presentation value in the frame header with its
+// single period equivalent, with the top field first if topFirst is true.
+func normalizePresentation(f *Frame, topFirst bool) *Frame {
+	if f.Header == nil || f.Header.I == nil {
+		return f
+	}
+	p := f.Header.I.Presentation
+	switch p {
+	case PresentTopFirstRepeat, PresentBottomFirstRepeat:
+		p = PresentBottomFirst
+		if topFirst {
+			p = PresentTopFirst
+		}
+	case PresentDouble, PresentTriple:
+		p = PresentSingle
+	default:
+		return f
+	}
+	i := *f.Header.I
+	i.Presentation = p
+	h := *f.Header
+	h.I = &i
+	h.Raw = h.Bytes()
+	g := *f
+	g.Header = &h
+	return &g
+}
+
+// weave builds a new frame from two fields of opposite parity, the first of which is
+// displayed first.
+func weave(first, second field) (*Frame, error) {
+	a, b := first.frame, second.frame
+	if a.Width != b.Width || a.Height != b.Height || a.Chroma != b.Chroma {
+		return nil, fmt.Errorf("cannot weave fields of %dx%d %s and %dx%d %s frames",
+			a.Width, a.Height, a.Chroma, b.Width, b.Height, b.Chroma)
+	}
+	topFrame, bottomFrame := a, b
+	if !first.top {
+		topFrame, bottomFrame = b, a
+	}
+	f := &Frame{Width: a.Width, Height: a.Height, Chroma: a.Chroma}
+	f.Y = weavePlanes(topFrame.Y, bottomFrame.Y, a.Width, a.Height)
+	if len(a.Cb) > 0 {
+		cw, ch := chromaDims(a.Width, a.Height, a.Chroma)
+		f.Cb = weavePlanes(topFrame.Cb, bottomFrame.Cb, cw, ch)
+		f.Cr = weavePlanes(topFrame.Cr, bottomFrame.Cr, cw, ch)
+	}
+	if len(a.Alpha) > 0 {
+		f.Alpha = weavePlanes(topFrame.Alpha, bottomFrame.Alpha, a.Width, a.Height)
+	}
+	presentation := PresentTopFirst
+	if !first.top {
+		presentation = PresentBottomFirst
+	}
+	f.Header = &FrameHeader{
+		MagicString: "FRAME",
+		I:           &IField{Presentation: presentation, Temporal: SamplingInterlaced, Spatial: SamplingInterlaced},
+	}
+	f.Header.Raw = f.Header.Bytes()
+	return f, nil
+}
+
+// weavePlanes returns a plane of width w and height h taking even lines from top and odd
+// lines from bottom.
+func weavePlanes(top, bottom []byte, w, h int) []byte {
+	out := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		src := top
+		if y%2 == 1 {
+			src = bottom
+		}
+		copy(out[y*w:(y+1)*w], src[y*w:(y+1)*w])
+	}
+	return out
+}
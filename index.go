@@ -0,0 +1,87 @@
+package y4m
+
+import (
+	"fmt"
+	"io"
+)
+
+// FrameIndex holds the byte offset of the start of each frame header in a stream, indexed
+// by frame number counting from zero.
+type FrameIndex []int64
+
+// BuildIndex scans the stream and records the byte offset of every frame. The read offset
+// of the stream file is restored afterwards.
+func (s *Stream) BuildIndex() (FrameIndex, error) {
+	if s.in != nil {
+		return nil, ErrNotSeekable
+	}
+	initPos, err := s.file.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	initFrame := s.frameIndex
+	err = s.ToFirstFrame()
+	if err != nil {
+		return nil, err
+	}
+	var idx FrameIndex
+	for {
+		pos, err := s.file.Seek(0, 1)
+		if err != nil {
+			return nil, err
+		}
+		err = s.SkipFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		idx = append(idx, pos)
+	}
+	_, err = s.file.Seek(initPos, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.frameIndex = initFrame
+	return idx, nil
+}
+
+// SeekFrame sets the read offset of the stream file to the beginning of frame n, counting
+// from zero, using the offsets recorded in idx.
+func (s *Stream) SeekFrame(idx FrameIndex, n int) error {
+	if s.in != nil {
+		return ErrNotSeekable
+	}
+	if n < 0 || n >= len(idx) {
+		return errFrameOutOfRange(n, len(idx))
+	}
+	_, err := s.file.Seek(idx[n], 0)
+	if err != nil {
+		return err
+	}
+	s.frameIndex = n
+	return nil
+}
+
+// ReadFrames parses the frames with the given frame numbers, counting from zero, seeking
+// directly to each one using idx rather than skipping through the frames in between. The
+// frames are returned in the order requested.
+func (s *Stream) ReadFrames(idx FrameIndex, frames []int) ([]*Frame, error) {
+	out := make([]*Frame, 0, len(frames))
+	for _, n := range frames {
+		err := s.SeekFrame(idx, n)
+		if err != nil {
+			return nil, err
+		}
+		f, err := s.ParseFrame()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func errFrameOutOfRange(n, count int) error {
+	return fmt.Errorf("frame %d is out of range; stream has %d frames", n, count)
+}
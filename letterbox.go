@@ -0,0 +1,103 @@
+package y4m
+
+import (
+	"fmt"
+	"image"
+)
+
+// ActiveArea returns the rectangle of frame f that remains after removing black borders, such
+// as the bars of letterboxed or pillarboxed content. A row or column at the edge of the frame
+// belongs to a border if the mean of its luma samples is at most threshold; 24 suits both
+// limited and full range content. A frame that is entirely black gives an empty rectangle.
+func ActiveArea(f *Frame, threshold int) image.Rectangle {
+	p := f.Plane(PlaneY)
+	rowMean := func(y int) int {
+		sum := 0
+		for _, v := range p.Row(y) {
+			sum += int(v)
+		}
+		return sum / p.Width
+	}
+	colMean := func(x, y0, y1 int) int {
+		sum := 0
+		for y := y0; y < y1; y++ {
+			sum += int(p.At(x, y))
+		}
+		return sum / (y1 - y0)
+	}
+	y0, y1 := 0, p.Height
+	for y0 < y1 && rowMean(y0) <= threshold {
+		y0++
+	}
+	for y1 > y0 && rowMean(y1-1) <= threshold {
+		y1--
+	}
+	if y0 == y1 {
+		return image.Rectangle{}
+	}
+	// Columns are measured over the active rows only, so that letterbox bars do not hide
+	// pillarbox bars
+	x0, x1 := 0, p.Width
+	for x0 < x1 && colMean(x0, y0, y1) <= threshold {
+		x0++
+	}
+	for x1 > x0 && colMean(x1-1, y0, y1) <= threshold {
+		x1--
+	}
+	return image.Rect(x0, y0, x1, y1)
+}
+
+// DetectActiveArea finds the black borders that are constant across the stream and returns the
+// active picture rectangle inside them, aligned inwards to the CropGrid of the stream so that
+// it can be passed to Frame.Crop. It examines n frames spread evenly over the stream and takes the
+// union of their active areas, ignoring frames that are entirely black, such as fades. If
+// every sampled frame is black, the whole frame is returned. The read offset of the stream is
+// restored afterwards. The stream must be seekable.
+func (s *Stream) DetectActiveArea(n, threshold int) (image.Rectangle, error) {
+	full := image.Rect(0, 0, s.Width, s.Height)
+	if n < 1 {
+		return full, fmt.Errorf("number of frames to examine must be positive")
+	}
+	idx, err := s.BuildIndex()
+	if err != nil {
+		return full, err
+	}
+	if len(idx) == 0 {
+		return full, fmt.Errorf("stream has no frames")
+	}
+	initPos, err := s.offset()
+	if err != nil {
+		return full, err
+	}
+	initFrame := s.frameIndex
+	var r image.Rectangle
+	n = minInt(n, len(idx))
+	for k := 0; k < n; k++ {
+		err = s.SeekFrame(idx, (2*k+1)*len(idx)/(2*n))
+		if err != nil {
+			return full, err
+		}
+		f, err := s.ParseFrame()
+		if err != nil {
+			return full, err
+		}
+		r = r.Union(ActiveArea(f, threshold))
+	}
+	_, err = s.file.Seek(initPos, 0)
+	if err != nil {
+		return full, err
+	}
+	s.frameIndex = initFrame
+	if r.Empty() {
+		return full, nil
+	}
+	xss, yss := s.CropGrid()
+	r.Min.X = (r.Min.X + xss - 1) / xss * xss
+	r.Min.Y = (r.Min.Y + yss - 1) / yss * yss
+	r.Max.X = r.Max.X / xss * xss
+	r.Max.Y = r.Max.Y / yss * yss
+	if r.Empty() {
+		return full, nil
+	}
+	return r, nil
+}
@@ -0,0 +1,77 @@
+package y4m
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// Limits bounds the memory that parsing the headers of a stream may use, so that a malformed
+// or hostile input cannot cause unbounded allocations. Headers that exceed a limit are
+// rejected with an error wrapping ErrLimitExceeded. A zero limit is not enforced.
+type Limits struct {
+	// MaxHeaderLength is the length in octets of the longest stream or frame header accepted,
+	// including the terminating '\n'.
+	MaxHeaderLength int
+	// MaxMetadata is the largest total length in octets of the X field values of a header.
+	MaxMetadata int
+	// MaxFrameSize is the largest frame, in octets of image data, that a stream header may
+	// declare. Frames are allocated at the size their stream header declares, so the limit
+	// rejects headers that declare more before any frame is read.
+	MaxFrameSize int64
+}
+
+// DefaultLimits are the limits given to streams opened for reading. Real headers are rarely
+// more than a few hundred octets long, and a 16384x16384 4:4:4 frame with alpha takes 1 GiB.
+var DefaultLimits = Limits{MaxHeaderLength: 64 << 10, MaxMetadata: 16 << 10, MaxFrameSize: 1 << 30}
+
+// checkMetadata checks the total length of the X field values m of a header.
+func (l Limits) checkMetadata(m []string) error {
+	if l.MaxMetadata <= 0 {
+		return nil
+	}
+	n := 0
+	for _, v := range m {
+		n += len(v)
+	}
+	if n > l.MaxMetadata {
+		return fmt.Errorf("%w: header has %d octets of metadata, more than %d", ErrLimitExceeded, n,
+			l.MaxMetadata)
+	}
+	return nil
+}
+
+// checkFrameSize checks the frame size declared by the header of stream s.
+func (l Limits) checkFrameSize(s *Stream) error {
+	if l.MaxFrameSize <= 0 {
+		return nil
+	}
+	if n := s.FrameImageDataSize(); n > l.MaxFrameSize {
+		return fmt.Errorf("%w: %dx%d %s frames have %d octets, more than %d", ErrLimitExceeded,
+			s.Width, s.Height, s.Chroma, n, l.MaxFrameSize)
+	}
+	return nil
+}
+
+// headerTooLong returns the error for a header longer than the limit of max octets.
+func headerTooLong(max int) error {
+	return fmt.Errorf("%w: header longer than %d octets", ErrLimitExceeded, max)
+}
+
+// readLine reads up to and including the next '\n' from r, like ReadBytes, but without
+// copying lines that fit in the buffer of r. The line is only valid until the next read. If
+// max is positive, a longer line is not read further, and the error wraps ErrLimitExceeded.
+func readLine(r *bufio.Reader, max int) ([]byte, error) {
+	b, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		b = append([]byte(nil), b...)
+		for err == bufio.ErrBufferFull && (max <= 0 || len(b) <= max) {
+			var more []byte
+			more, err = r.ReadSlice('\n')
+			b = append(b, more...)
+		}
+	}
+	if max > 0 && len(b) > max {
+		return b, headerTooLong(max)
+	}
+	return b, err
+}
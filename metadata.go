@@ -0,0 +1,156 @@
+package y4m
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tags returns the X metadata fields of the stream header, in the form KEY=value and in the
+// order Header writes them: the well-known tags stored in typed stream fields, such as YSCSS,
+// COLORRANGE and the HDR tags, followed by the fields in Metadata.
+func (s *Stream) Tags() []string {
+	return append(s.typedTags(), s.Metadata...)
+}
+
+// MetadataValue returns the value of the X metadata field KEY=value in the frame header, and
+// reports whether the field is present. A field without '=' has an empty value.
+func (h *FrameHeader) MetadataValue(key string) (string, bool) {
+	for _, m := range h.Metadata {
+		k, v := splitMetadata(m)
+		if k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// SetMetadata sets the X metadata field KEY=value in the frame header, replacing an existing
+// field with the same key or else appending a new one. The header's raw bytes are regenerated
+// so that the field is written. Keys and values cannot contain spaces, and keys cannot contain
+// '='.
+func (h *FrameHeader) SetMetadata(key, value string) error {
+	err := checkMetadata(key, value)
+	if err != nil {
+		return err
+	}
+	h.Metadata = setMetadataField(h.Metadata, key, value)
+	h.Raw = h.Bytes()
+	return nil
+}
+
+// DeleteMetadata removes the X metadata fields with the given key from the frame header.
+func (h *FrameHeader) DeleteMetadata(key string) {
+	var deleted bool
+	h.Metadata, deleted = deleteMetadataField(h.Metadata, key)
+	if deleted {
+		h.Raw = h.Bytes()
+	}
+}
+
+// SetMetadata sets the X metadata field KEY=value in the frame's header, creating a bare
+// "FRAME" header if the frame has none. See FrameHeader.SetMetadata.
+func (f *Frame) SetMetadata(key, value string) error {
+	if f.Header == nil {
+		f.Header = &FrameHeader{MagicString: "FRAME"}
+	}
+	return f.Header.SetMetadata(key, value)
+}
+
+// MetadataValue returns the value of the X metadata field KEY=value in the stream header, and
+// reports whether the field is present. Well-known tags stored in typed stream fields, such as
+// YSCSS, COLORRANGE and the HDR tags, are included.
+func (s *Stream) MetadataValue(key string) (string, bool) {
+	for _, m := range s.Tags() {
+		k, v := splitMetadata(m)
+		if k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// SetMetadata sets the X metadata field KEY=value in the stream header, replacing an existing
+// field with the same key or else appending a new one. A well-known tag with a recognized value
+// sets the corresponding typed stream field instead. The header must be written afterwards for
+// the change to take effect.
+func (s *Stream) SetMetadata(key, value string) error {
+	err := checkMetadata(key, value)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "YSCSS", "COLORRANGE", "MASTERING_DISPLAY", "MAXCLL", "MAXFALL":
+		// An unrecognized value is kept as a plain field, so the typed field is cleared
+		s.DeleteMetadata(key)
+		if !s.parseTag(key + "=" + value) {
+			s.Metadata = append(s.Metadata, key+"="+value)
+		}
+		return nil
+	}
+	s.Metadata = setMetadataField(s.Metadata, key, value)
+	return nil
+}
+
+// DeleteMetadata removes the X metadata fields with the given key from the stream header,
+// clearing the corresponding typed stream field for a well-known tag.
+func (s *Stream) DeleteMetadata(key string) {
+	switch key {
+	case "YSCSS":
+		s.YSCSS = ""
+	case "COLORRANGE":
+		s.ColorRange = ColorRangeUnspecified
+	case "MASTERING_DISPLAY":
+		s.MasteringDisplay = nil
+	case "MAXCLL":
+		s.MaxCLL = 0
+	case "MAXFALL":
+		s.MaxFALL = 0
+	}
+	s.Metadata, _ = deleteMetadataField(s.Metadata, key)
+}
+
+// setMetadataField returns fields with the field KEY=value replacing the first field with the
+// same key, or appended if there is none. The slice passed in is not modified.
+func setMetadataField(fields []string, key, value string) []string {
+	field := key + "=" + value
+	fields = append([]string(nil), fields...)
+	for k, m := range fields {
+		if mk, _ := splitMetadata(m); mk == key {
+			fields[k] = field
+			return fields
+		}
+	}
+	return append(fields, field)
+}
+
+// deleteMetadataField returns fields without the fields with the given key, and reports
+// whether any were removed.
+func deleteMetadataField(fields []string, key string) ([]string, bool) {
+	var kept []string
+	for _, m := range fields {
+		if k, _ := splitMetadata(m); k != key {
+			kept = append(kept, m)
+		}
+	}
+	return kept, len(kept) != len(fields)
+}
+
+// splitMetadata splits X metadata field m into its key and value.
+func splitMetadata(m string) (key, value string) {
+	if n := strings.IndexByte(m, '='); n >= 0 {
+		return m[:n], m[n+1:]
+	}
+	return m, ""
+}
+
+// checkMetadata checks that an X metadata field with the given key and value can be written
+// in a header.
+func checkMetadata(key, value string) error {
+	if key == "" || strings.ContainsAny(key, "= \t\n\r") {
+		return fmt.Errorf("invalid metadata key %q", key)
+	}
+	if strings.ContainsAny(value, " \t\n\r") {
+		return fmt.Errorf("invalid metadata value %q for key %s", value, key)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package y4m
+
+import (
+	"fmt"
+	"math"
+)
+
+// Metrics holds a quality measurement for each plane of a frame and for the frame as a whole.
+// All combines the planes weighted by their number of samples. The chroma values of mono
+// frames are zero.
+type Metrics struct {
+	Y   float64
+	Cb  float64
+	Cr  float64
+	All float64
+}
+
+// PSNR returns the peak signal-to-noise ratio in decibels of frame dist relative to frame
+// ref. All is computed from the mean squared error over the samples of all planes. Identical
+// planes give +Inf. The frames must have the same geometry and chroma format; alpha is ignored.
+func PSNR(ref, dist *Frame) (Metrics, error) {
+	var m Metrics
+	err := checkComparable(ref, dist)
+	if err != nil {
+		return m, err
+	}
+	var sum float64
+	var n int
+	out := []*float64{&m.Y, &m.Cb, &m.Cr}
+	for i := PlaneY; i <= PlaneCr; i++ {
+		a, b := ref.Plane(i), dist.Plane(i)
+		if a.Data == nil {
+			continue
+		}
+		sse := sumSquaredError(a, b)
+		*out[i] = psnr(sse, a.Width*a.Height)
+		sum += sse
+		n += a.Width * a.Height
+	}
+	m.All = psnr(sum, n)
+	return m, nil
+}
+
+// SSIM returns the structural similarity index of frame dist relative to frame ref, computed
+// over 8x8 windows spaced 4 samples apart. The frames must have the same geometry and chroma
+// format; alpha is ignored.
+func SSIM(ref, dist *Frame) (Metrics, error) {
+	var m Metrics
+	err := checkComparable(ref, dist)
+	if err != nil {
+		return m, err
+	}
+	var sum float64
+	var n int
+	out := []*float64{&m.Y, &m.Cb, &m.Cr}
+	for i := PlaneY; i <= PlaneCr; i++ {
+		a, b := ref.Plane(i), dist.Plane(i)
+		if a.Data == nil {
+			continue
+		}
+		*out[i] = planeSSIM(a, b)
+		sum += *out[i] * float64(a.Width*a.Height)
+		n += a.Width * a.Height
+	}
+	m.All = sum / float64(n)
+	return m, nil
+}
+
+// checkComparable checks that frames f and g have the same geometry and chroma format.
+func checkComparable(f, g *Frame) error {
+	if f.Width != g.Width || f.Height != g.Height || f.Chroma != g.Chroma {
+		return fmt.Errorf("cannot compare %dx%d %s frame with %dx%d %s frame",
+			f.Width, f.Height, f.Chroma, g.Width, g.Height, g.Chroma)
+	}
+	return nil
+}
+
+func sumSquaredError(a, b Plane) float64 {
+	var sse float64
+	for y := 0; y < a.Height; y++ {
+		ra, rb := a.Row(y), b.Row(y)
+		for x := range ra {
+			d := float64(ra[x]) - float64(rb[x])
+			sse += d * d
+		}
+	}
+	return sse
+}
+
+// psnr converts the sum of squared errors over n samples into a PSNR in decibels.
+func psnr(sse float64, n int) float64 {
+	if sse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255*float64(n)/sse)
+}
+
+// planeSSIM returns the mean SSIM of planes a and b over 8x8 windows spaced 4 samples apart.
+// Planes smaller than a window are treated as a single window.
+func planeSSIM(a, b Plane) float64 {
+	const c1, c2 = (0.01 * 255) * (0.01 * 255), (0.03 * 255) * (0.03 * 255)
+	ww, wh := minInt(8, a.Width), minInt(8, a.Height)
+	var total float64
+	var windows int
+	for y0 := 0; y0+wh <= a.Height; y0 += 4 {
+		for x0 := 0; x0+ww <= a.Width; x0 += 4 {
+			var sa, sb, saa, sbb, sab float64
+			for y := y0; y < y0+wh; y++ {
+				for x := x0; x < x0+ww; x++ {
+					va, vb := float64(a.At(x, y)), float64(b.At(x, y))
+					sa += va
+					sb += vb
+					saa += va * va
+					sbb += vb * vb
+					sab += va * vb
+				}
+			}
+			n := float64(ww * wh)
+			ma, mb := sa/n, sb/n
+			va, vb := saa/n-ma*ma, sbb/n-mb*mb
+			cov := sab/n - ma*mb
+			total += (2*ma*mb + c1) * (2*cov + c2) / ((ma*ma + mb*mb + c1) * (va + vb + c2))
+			windows++
+		}
+	}
+	return total / float64(windows)
+}
@@ -0,0 +1,88 @@
+package y4m
+
+import (
+	"bytes"
+	"io"
+)
+
+// OpenMapped opens a named file for reading, parses the header, and maps the file into
+// memory. The planes of frames parsed from a mapped stream are slices of the mapping rather
+// than copies, which avoids I/O and allocation when frames are accessed repeatedly or at
+// random. The mapping is read-only: the planes must not be modified, so methods that write
+// to planes in place, such as Overlay, must only be used on a copy of the frame. Planes must
+// not be used after the stream is closed. On platforms without memory mapping, and for files
+// that cannot seek, such as named pipes, OpenMapped behaves like Open.
+func OpenMapped(name string) (*Stream, error) {
+	s, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.in != nil {
+		return s, nil
+	}
+	s.mapping, err = mmapFile(s.file)
+	if err != nil {
+		s.file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Mapped reports whether the stream is backed by a memory mapping.
+func (s *Stream) Mapped() bool {
+	return s.mapping != nil
+}
+
+// parseMappedFrame parses the frame beginning at offset directly from the mapping, and
+// advances the read offset past it.
+func (s *Stream) parseMappedFrame(offset int64) (*Frame, error) {
+	m := s.mapping
+	if offset >= int64(len(m)) {
+		return nil, io.EOF
+	}
+	rest := m[offset:]
+	if max := s.Limits.MaxHeaderLength; max > 0 && len(rest) > max {
+		rest = rest[:max]
+	}
+	n := bytes.IndexByte(rest, '\n')
+	if n < 0 && len(rest) < len(m[offset:]) {
+		s.file.Seek(offset+int64(len(rest)), 0)
+		return nil, s.frameError(offset, headerTooLong(len(rest)))
+	} else if n < 0 {
+		return nil, s.frameError(offset, ErrTruncatedFrame)
+	}
+	pos := offset + int64(n) + 1
+	h, err := s.parseFrameLine(m[offset:pos:pos])
+	if err != nil {
+		s.file.Seek(pos, 0)
+		return nil, s.frameError(offset, err)
+	}
+	if pos+s.FrameImageDataSize() > int64(len(m)) {
+		s.file.Seek(int64(len(m)), 0)
+		return nil, s.frameError(offset, ErrTruncatedFrame)
+	}
+	plane := func(size int) []byte {
+		if size == 0 {
+			return nil
+		}
+		p := m[pos : pos+int64(size) : pos+int64(size)]
+		pos += int64(size)
+		return p
+	}
+	frame := &Frame{
+		Header: h,
+		Width:  s.Width,
+		Height: s.Height,
+		Chroma: s.Chroma,
+	}
+	frame.Y = plane(s.LumaPlaneSize())
+	frame.Cb = plane(s.ChromaPlaneSize())
+	frame.Cr = plane(s.ChromaPlaneSize())
+	frame.Alpha = plane(s.AlphaPlaneSize())
+	_, err = s.file.Seek(pos, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.frameIndex++
+	return frame, nil
+}
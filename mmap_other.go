@@ -0,0 +1,17 @@
+//go:build !unix
+
+package y4m
+
+import (
+	"os"
+)
+
+// mmapFile does not map the file on platforms without memory mapping support, so frames are
+// read from the file as usual.
+func mmapFile(f *os.File) ([]byte, error) {
+	return nil, nil
+}
+
+func munmap(b []byte) error {
+	return nil
+}
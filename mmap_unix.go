@@ -0,0 +1,24 @@
+//go:build unix
+
+package y4m
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps file f into memory read-only. An empty file is not mapped.
+func mmapFile(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		return nil, nil
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
@@ -0,0 +1,16 @@
+package y4m
+
+// ToMono converts the frame to the mono chroma format by dropping its chroma planes, giving a
+// luma-only frame for analysis or for monochrome content, which needs no chroma. An alpha
+// plane is dropped as well, since mono frames cannot carry one.
+func (f *Frame) ToMono() {
+	f.Cb, f.Cr, f.Alpha = nil, nil, nil
+	f.Chroma = "mono"
+}
+
+// ToMono updates the header fields of an output stream to hold frames converted with
+// Frame.ToMono. An XYSCSS tag is rewritten to MONO, as written by mjpegtools for monochrome
+// streams. It should be called on an output stream before its header is written.
+func (s *Stream) ToMono() {
+	s.ConvertChroma("mono")
+}
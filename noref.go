@@ -0,0 +1,91 @@
+package y4m
+
+// The no-reference metrics below judge a decoded frame on its own, without the pristine
+// source that PSNR and SSIM need. They are crude, but enough for quality control to flag
+// obviously broken segments of decoder output.
+
+// Blockiness returns a measure of the 8x8 block artifacts in the luma plane of frame f: the
+// mean absolute difference between neighbouring samples across block boundaries, divided by
+// the mean absolute difference between neighbouring samples within blocks. Content without
+// block artifacts scores about 1; heavily compressed content with visible block edges scores
+// well above it. A flat frame, which has no differences at all, scores 1.
+func Blockiness(f *Frame) float64 {
+	p := f.Plane(PlaneY)
+	var edge, inner float64
+	var nEdge, nInner int
+	add := func(a, b byte, boundary bool) {
+		d := float64(absInt(int(a) - int(b)))
+		if boundary {
+			edge += d
+			nEdge++
+		} else {
+			inner += d
+			nInner++
+		}
+	}
+	for y := 0; y < p.Height; y++ {
+		row := p.Row(y)
+		for x := 1; x < p.Width; x++ {
+			add(row[x-1], row[x], x%8 == 0)
+		}
+		if y > 0 {
+			prev := p.Row(y - 1)
+			for x := range row {
+				add(prev[x], row[x], y%8 == 0)
+			}
+		}
+	}
+	switch {
+	case nEdge == 0 || nInner == 0 || edge == 0:
+		return 1
+	case inner == 0:
+		// Block edges between perfectly flat blocks; report the edge strength alone
+		return edge / float64(nEdge)
+	}
+	return (edge / float64(nEdge)) / (inner / float64(nInner))
+}
+
+// edgeThreshold is the smallest difference between horizontally neighbouring luma samples
+// that EdgeWidth treats as part of an edge.
+const edgeThreshold = 24
+
+// EdgeWidth estimates the blur of the luma plane of frame f as the mean width, in samples, of
+// its vertical edges. At each sample where the horizontal gradient peaks above a threshold,
+// the edge is followed left and right while the luma keeps rising or falling, and its width is
+// the distance between the ends. Sharp content has edges one or two samples wide; blurred or
+// heavily filtered content has wider ones. A frame without edges gives zero.
+func EdgeWidth(f *Frame) float64 {
+	p := f.Plane(PlaneY)
+	var total, n int
+	for y := 0; y < p.Height; y++ {
+		row := p.Row(y)
+		grad := func(x int) int { return int(row[x+1]) - int(row[x]) }
+		for x := 0; x+1 < p.Width; x++ {
+			g := grad(x)
+			if absInt(g) < edgeThreshold {
+				continue
+			}
+			// Only measure each edge once, at its steepest step
+			if (x > 0 && absInt(grad(x-1)) > absInt(g)) || (x+2 < p.Width && absInt(grad(x+1)) >= absInt(g)) {
+				continue
+			}
+			sign := 1
+			if g < 0 {
+				sign = -1
+			}
+			left, right := x, x+1
+			for left > 0 && sign*(int(row[left])-int(row[left-1])) > 0 {
+				left--
+			}
+			for right+1 < p.Width && sign*(int(row[right+1])-int(row[right])) > 0 {
+				right++
+			}
+			total += right - left
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(total) / float64(n)
+}
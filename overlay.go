@@ -0,0 +1,80 @@
+package y4m
+
+// Overlay composites frame src onto the frame with its top left corner at (x, y). Parts of
+// src falling outside the frame are clipped. When src has an alpha plane, each sample is
+// blended with the underlying image according to its alpha value; otherwise src replaces the
+// covered region. Chroma samples are taken from the nearest src sample, so src and the frame
+// may use different chroma formats. If the frame has an alpha plane, the composited alpha is
+// stored in it. An error is returned, and the frame left unchanged, if either frame's chroma
+// format is unknown.
+func (f *Frame) Overlay(src *Frame, x, y int) error {
+	xss, yss, err := subsampling(f.Chroma)
+	if err != nil {
+		return err
+	}
+	sxss, syss, err := subsampling(src.Chroma)
+	if err != nil {
+		return err
+	}
+	x0, y0 := maxInt(x, 0), maxInt(y, 0)
+	x1, y1 := minInt(x+src.Width, f.Width), minInt(y+src.Height, f.Height)
+	if x0 >= x1 || y0 >= y1 {
+		return nil
+	}
+	alpha := func(px, py int) int {
+		if len(src.Alpha) == 0 {
+			return 0xff
+		}
+		return int(src.Alpha[(py-y)*src.Width+px-x])
+	}
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			a := alpha(px, py)
+			k := py*f.Width + px
+			f.Y[k] = blend(src.Y[(py-y)*src.Width+px-x], f.Y[k], a)
+			if len(f.Alpha) > 0 {
+				f.Alpha[k] = byte(a + (int(f.Alpha[k])*(0xff-a)+0x7f)/0xff)
+			}
+		}
+	}
+	if len(f.Cb) == 0 {
+		return nil
+	}
+	cw, _ := chromaDims(f.Width, f.Height, f.Chroma)
+	scw, _ := chromaDims(src.Width, src.Height, src.Chroma)
+	for cy := y0 / yss; cy*yss < y1; cy++ {
+		for cx := x0 / xss; cx*xss < x1; cx++ {
+			// Use the src sample co-sited with the top left luma sample of the chroma block
+			px, py := maxInt(cx*xss, x0), maxInt(cy*yss, y0)
+			a := alpha(px, py)
+			cb, cr := byte(0x80), byte(0x80)
+			if len(src.Cb) > 0 {
+				sk := (py-y)/syss*scw + (px-x)/sxss
+				cb, cr = src.Cb[sk], src.Cr[sk]
+			}
+			k := cy*cw + cx
+			f.Cb[k] = blend(cb, f.Cb[k], a)
+			f.Cr[k] = blend(cr, f.Cr[k], a)
+		}
+	}
+	return nil
+}
+
+// blend mixes sample s over sample d with alpha a in the range [0, 255].
+func blend(s, d byte, a int) byte {
+	return byte((int(s)*a + int(d)*(0xff-a) + 0x7f) / 0xff)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,109 @@
+package y4m
+
+import (
+	"bufio"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// DecodeParallel decodes every frame of the stream on several goroutines and calls fn with
+// each frame and its number, counting from zero, for batch analysis of long streams on
+// multiple cores. The frames are divided into workers disjoint ranges of consecutive frames
+// using the offsets recorded in idx, and each range is read sequentially from its own section
+// of the stream file by its own goroutine, so fn is called concurrently, in no particular
+// order, and must be safe for concurrent use. A workers value of zero or less uses one
+// goroutine per CPU. The read offset of the stream is not changed. If fn or decoding returns
+// an error, the remaining ranges stop at their next frame and the first error is returned.
+// The stream must be seekable.
+func (s *Stream) DecodeParallel(idx FrameIndex, workers int, fn func(n int, f *Frame) error) error {
+	if s.in != nil {
+		return ErrNotSeekable
+	}
+	if len(idx) == 0 {
+		return nil
+	}
+	fi, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = minInt(workers, len(idx))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+	for k := 0; k < workers; k++ {
+		start, end := k*len(idx)/workers, (k+1)*len(idx)/workers
+		to := size
+		if end < len(idx) {
+			to = idx[end]
+		}
+		r := bufio.NewReaderSize(io.NewSectionReader(s.file, idx[start], to-idx[start]), defaultWriteBufferSize)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := start; n < end && !failed(); n++ {
+				f, err := s.readFrameFrom(r)
+				if err == nil {
+					err = fn(n, f)
+				} else {
+					err = newFrameError(n, idx[n], err)
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// readFrameFrom parses a frame of the stream read from r. A frame cut short by the end of r
+// gives ErrTruncatedFrame and any other read error is returned unchanged, for the caller to
+// wrap in a FrameError with the frame's number and offset.
+func (s *Stream) readFrameFrom(r *bufio.Reader) (*Frame, error) {
+	hs, err := readLine(r, s.Limits.MaxHeaderLength)
+	if err == io.EOF {
+		return nil, ErrTruncatedFrame
+	} else if err != nil {
+		return nil, err
+	}
+	if !s.discardRaw {
+		hs = append([]byte(nil), hs...)
+	}
+	h, err := s.parseFrameLine(hs)
+	if err != nil {
+		return nil, err
+	}
+	frame := &Frame{Header: h, Width: s.Width, Height: s.Height, Chroma: s.Chroma}
+	planes := []*[]byte{&frame.Y, &frame.Cb, &frame.Cr, &frame.Alpha}
+	sizes := []int{s.LumaPlaneSize(), s.ChromaPlaneSize(), s.ChromaPlaneSize(), s.AlphaPlaneSize()}
+	for k, size := range sizes {
+		if size == 0 {
+			continue
+		}
+		*planes[k] = make([]byte, size)
+		_, err = io.ReadFull(r, *planes[k])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// The frame header has been read, so the section ending here truncates the frame
+			return nil, ErrTruncatedFrame
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
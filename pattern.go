@@ -0,0 +1,120 @@
+package y4m
+
+import (
+	"math"
+)
+
+// The test pattern generators produce frames with limited range samples, as broadcast
+// equipment expects, using the BT.601 conversion from RGB.
+
+// ColorBars returns a frame of SMPTE color bars: seven 75% bars over the upper two thirds,
+// a strip of reversed blue bars, and a bottom row with -I, 100% white, +Q and a PLUGE of
+// -4%, 0% and +4% black.
+func ColorBars(w, h int, chroma string) (*Frame, error) {
+	gray, yellow, cyan := studio(.75, .75, .75), studio(.75, .75, 0), studio(0, .75, .75)
+	green, magenta, red, blue := studio(0, .75, 0), studio(.75, 0, .75), studio(.75, 0, 0), studio(0, 0, .75)
+	black, white := studio(0, 0, 0), studio(1, 1, 1)
+	bars := [][3]byte{gray, yellow, cyan, green, magenta, red, blue}
+	strip := [][3]byte{blue, black, magenta, black, cyan, black, gray}
+	negI, posQ := studio(0, .2148, .3135), studio(.2088, 0, .4248)
+	below, above := [3]byte{7, 128, 128}, [3]byte{25, 128, 128}
+	return patternFrame(w, h, chroma, func(x, y int) [3]byte {
+		bar := x * 7 / w
+		switch {
+		case y < h*2/3:
+			return bars[bar]
+		case y < h*3/4:
+			return strip[bar]
+		}
+		// The bottom row divides the width into four 5/4 bar sections, three 1/3 bar PLUGE
+		// sections and a final black bar
+		switch u := x * 28 / w; {
+		case u < 5:
+			return negI
+		case u < 10:
+			return white
+		case u < 15:
+			return posQ
+		case u < 20:
+			return black
+		}
+		switch p := x * 21 / w; {
+		case p == 15:
+			return below
+		case p == 17:
+			return above
+		}
+		return black
+	})
+}
+
+// Gradient returns a frame with a horizontal luma ramp from black on the left to white on
+// the right, and neutral chroma.
+func Gradient(w, h int, chroma string) (*Frame, error) {
+	return patternFrame(w, h, chroma, func(x, y int) [3]byte {
+		if w == 1 {
+			return [3]byte{16, 128, 128}
+		}
+		return [3]byte{byte(16 + roundDiv(219*x, w-1)), 128, 128}
+	})
+}
+
+// ZonePlate returns frame n of a moving circular zone plate, whose luma frequency rises from
+// zero at the centre to the Nyquist limit at the nearest edge. The rings move outwards by one
+// sixteenth of a cycle each frame.
+func ZonePlate(w, h int, chroma string, n int) (*Frame, error) {
+	r := float64(minInt(w, h)) / 2
+	return patternFrame(w, h, chroma, func(x, y int) [3]byte {
+		dx, dy := float64(x)-float64(w)/2, float64(y)-float64(h)/2
+		phase := math.Pi*(dx*dx+dy*dy)/(2*r) - float64(n)*math.Pi/8
+		return [3]byte{byte(125.5 + 109.5*math.Cos(phase)), 128, 128}
+	})
+}
+
+// Checkerboard returns a frame of alternating black and white squares of the given size,
+// with a white square at the top left.
+func Checkerboard(w, h int, chroma string, size int) (*Frame, error) {
+	if size < 1 {
+		size = 1
+	}
+	return patternFrame(w, h, chroma, func(x, y int) [3]byte {
+		if (x/size+y/size)%2 == 0 {
+			return [3]byte{235, 128, 128}
+		}
+		return [3]byte{16, 128, 128}
+	})
+}
+
+// patternFrame returns a frame whose samples are given by c, which returns the Y, Cb and Cr
+// values at each luma sample position. Chroma is downsampled by averaging.
+func patternFrame(w, h int, chroma string, c func(x, y int) [3]byte) (*Frame, error) {
+	f, err := NewFrame(w, h, chroma)
+	if err != nil {
+		return nil, err
+	}
+	cb := make([]byte, w*h)
+	cr := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := c(x, y)
+			k := y*w + x
+			f.Y[k], cb[k], cr[k] = v[0], v[1], v[2]
+		}
+	}
+	if len(f.Cb) > 0 {
+		xss, yss := xSubsamplingFactor[chroma], ySubsamplingFactor[chroma]
+		downsample(f.Cb, cb, w, h, xss, yss)
+		downsample(f.Cr, cr, w, h, xss, yss)
+	}
+	return f, nil
+}
+
+// studio converts gamma corrected R'G'B' values in [0, 1] to limited range BT.601 Y'CbCr.
+func studio(r, g, b float64) [3]byte {
+	y := 0.299*r + 0.587*g + 0.114*b
+	return [3]byte{
+		byte(math.Round(16 + 219*y)),
+		byte(math.Round(128 + 224*(b-y)/1.772)),
+		byte(math.Round(128 + 224*(r-y)/1.402)),
+	}
+}
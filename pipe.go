@@ -0,0 +1,43 @@
+package y4m
+
+import (
+	"bufio"
+	"io"
+)
+
+// OpenReader parses the header of a stream read from r, which need not be seekable, such as
+// standard input or a pipe. The frames of the returned stream can only be read in order, with
+// ParseFrame, ParseFrameHeader and SkipFrame; methods that seek, such as CountFrames,
+// BuildIndex and Trim, return ErrNotSeekable. In recovery mode, each frame is buffered in full
+// before it is parsed. Close does not close r.
+func OpenReader(r io.Reader) (*Stream, error) {
+	s := &Stream{in: bufio.NewReaderSize(r, defaultWriteBufferSize), Limits: DefaultLimits}
+	sb, err := s.in.Peek(len(streamMagicString))
+	if err != nil && len(sb) == 0 {
+		return nil, err
+	}
+	if string(sb) != streamMagicString {
+		return nil, ErrInvalidFormat
+	}
+	b, err := readLine(s.in, s.Limits.MaxHeaderLength)
+	s.pos += int64(len(b))
+	if err != nil {
+		return nil, err
+	}
+	err = s.parseHeaderBytes(append([]byte(nil), b...))
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStreamWriter creates a new stream with width w and height h that is written to wr, such
+// as standard output or a pipe. Writes are buffered as with NewStream; Close flushes the
+// buffer but does not close wr.
+func NewStreamWriter(wr io.Writer, w, h int) *Stream {
+	s := new(Stream)
+	s.w = bufio.NewWriterSize(wr, defaultWriteBufferSize)
+	s.Width = w
+	s.Height = h
+	return s
+}
@@ -0,0 +1,185 @@
+package y4m
+
+import (
+	"fmt"
+	"image"
+)
+
+// Plane indices for Frame.Plane.
+const (
+	PlaneY = iota
+	PlaneCb
+	PlaneCr
+	PlaneAlpha
+)
+
+// PlaneName returns the name of plane i, "Y", "Cb", "Cr" or "Alpha", for use in messages.
+func PlaneName(i int) string {
+	switch i {
+	case PlaneY:
+		return "Y"
+	case PlaneCb:
+		return "Cb"
+	case PlaneCr:
+		return "Cr"
+	case PlaneAlpha:
+		return "Alpha"
+	}
+	return fmt.Sprintf("plane %d", i)
+}
+
+// Plane describes a rectangular array of 8-bit samples. Sample (x, y) is stored at
+// Data[y*Stride+x], so rows may be separated by padding or belong to a larger plane.
+type Plane struct {
+	Data   []byte
+	Width  int
+	Height int
+	Stride int
+}
+
+// Plane returns plane i of the frame, which is one of PlaneY, PlaneCb, PlaneCr or PlaneAlpha.
+// The plane shares the frame's data. A plane the frame does not have, such as the chroma
+// planes of a mono frame, is returned with nil Data and zero size.
+func (f *Frame) Plane(i int) Plane {
+	switch i {
+	case PlaneY:
+		return Plane{Data: f.Y, Width: f.Width, Height: f.Height, Stride: f.Width}
+	case PlaneCb, PlaneCr:
+		data := f.Cb
+		if i == PlaneCr {
+			data = f.Cr
+		}
+		if len(data) == 0 {
+			return Plane{}
+		}
+		w, h := chromaDims(f.Width, f.Height, f.Chroma)
+		return Plane{Data: data, Width: w, Height: h, Stride: w}
+	case PlaneAlpha:
+		if len(f.Alpha) == 0 {
+			return Plane{}
+		}
+		return Plane{Data: f.Alpha, Width: f.Width, Height: f.Height, Stride: f.Width}
+	}
+	return Plane{}
+}
+
+// FillPlane sets every sample of plane i of the frame, which is one of PlaneY, PlaneCb, PlaneCr
+// or PlaneAlpha, to v. Filling both chroma planes with 128 removes all color from a frame.
+func (f *Frame) FillPlane(i int, v byte) error {
+	p := f.Plane(i)
+	if p.Data == nil {
+		return fmt.Errorf("%s frame has no plane %d", f.Chroma, i)
+	}
+	for k := range p.Data {
+		p.Data[k] = v
+	}
+	return nil
+}
+
+// SwapChroma exchanges the Cb and Cr planes of the frame, which turns a frame whose planes
+// were stored in YCrCb order, as by YV12 sources, into a correct one and helps diagnose plane
+// order bugs.
+func (f *Frame) SwapChroma() {
+	f.Cb, f.Cr = f.Cr, f.Cb
+}
+
+// At returns the sample at (x, y).
+func (p Plane) At(x, y int) byte {
+	return p.Data[y*p.Stride+x]
+}
+
+// Set sets the sample at (x, y) to v.
+func (p Plane) Set(x, y int, v byte) {
+	p.Data[y*p.Stride+x] = v
+}
+
+// Row returns the samples of row y.
+func (p Plane) Row(y int) []byte {
+	return p.Data[y*p.Stride : y*p.Stride+p.Width]
+}
+
+// SubPlane returns a view of the w by h rectangle of p whose top left sample is (x, y). The
+// view shares p's data.
+func (p Plane) SubPlane(x, y, w, h int) Plane {
+	if p.Data == nil || w <= 0 || h <= 0 {
+		return Plane{}
+	}
+	start := y*p.Stride + x
+	end := (y+h-1)*p.Stride + x + w
+	return Plane{Data: p.Data[start:end], Width: w, Height: h, Stride: p.Stride}
+}
+
+// Packed returns the plane's samples with rows stored contiguously, as Y4M frame data
+// requires. If the plane is already packed its data is returned without copying.
+func (p Plane) Packed() []byte {
+	if p.Stride == p.Width {
+		return p.Data[:p.Width*p.Height]
+	}
+	out := make([]byte, 0, p.Width*p.Height)
+	for y := 0; y < p.Height; y++ {
+		out = append(out, p.Row(y)...)
+	}
+	return out
+}
+
+// FrameView is a view of a rectangular region of a frame. Its planes share the frame's data,
+// so creating a view copies no samples and changes to the frame are visible through it.
+type FrameView struct {
+	Header *FrameHeader
+	Width  int
+	Height int
+	Chroma string
+	planes [4]Plane
+}
+
+// CropView returns a view of the region r of the frame, without copying plane data. The
+// region must lie within the frame and be aligned to the chroma subsampling. Use Materialize
+// to obtain a frame with packed planes, for example for writing.
+func (f *Frame) CropView(r image.Rectangle) (*FrameView, error) {
+	if !r.In(image.Rect(0, 0, f.Width, f.Height)) || r.Empty() {
+		return nil, fmt.Errorf("crop rectangle %v is not within %dx%d frame", r, f.Width, f.Height)
+	}
+	v := &FrameView{Header: f.Header, Width: r.Dx(), Height: r.Dy(), Chroma: f.Chroma}
+	v.planes[PlaneY] = f.Plane(PlaneY).SubPlane(r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+	v.planes[PlaneAlpha] = f.Plane(PlaneAlpha).SubPlane(r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+	if len(f.Cb) > 0 {
+		xss, yss, err := subsampling(f.Chroma)
+		if err != nil {
+			return nil, err
+		}
+		if r.Min.X%xss != 0 || r.Min.Y%yss != 0 || r.Dx()%xss != 0 || r.Dy()%yss != 0 {
+			return nil, fmt.Errorf("crop rectangle %v is not aligned to %s chroma subsampling (%dx%d)",
+				r, f.Chroma, xss, yss)
+		}
+		for _, i := range []int{PlaneCb, PlaneCr} {
+			v.planes[i] = f.Plane(i).SubPlane(r.Min.X/xss, r.Min.Y/yss, r.Dx()/xss, r.Dy()/yss)
+		}
+	}
+	return v, nil
+}
+
+// Plane returns plane i of the view, which is one of PlaneY, PlaneCb, PlaneCr or PlaneAlpha.
+func (v *FrameView) Plane(i int) Plane {
+	if i < PlaneY || i > PlaneAlpha {
+		return Plane{}
+	}
+	return v.planes[i]
+}
+
+// Materialize returns a new frame holding a packed copy of the view's planes. The frame
+// shares the view's header.
+func (v *FrameView) Materialize() *Frame {
+	f := &Frame{Header: v.Header, Width: v.Width, Height: v.Height, Chroma: v.Chroma}
+	dst := []*[]byte{&f.Y, &f.Cb, &f.Cr, &f.Alpha}
+	for i, p := range v.planes {
+		if p.Data == nil {
+			continue
+		}
+		if p.Stride == p.Width {
+			*dst[i] = copyBytes(p.Packed())
+		} else {
+			*dst[i] = p.Packed()
+		}
+	}
+	return f
+}
@@ -0,0 +1,80 @@
+package y4m
+
+import (
+	"math/rand"
+)
+
+// RandomChromas lists the chroma formats that RandomFrame and WriteRandomStream accept.
+var RandomChromas = []string{"444", "444alpha", "422", "411", "420jpeg", "420mpeg2", "420paldv", "mono"}
+
+// RandomFrame returns a frame of width w and height h in the given chroma format, with every
+// plane filled with pseudo-random data drawn from r. Using a seeded r makes the frame
+// reproducible, which is useful for property-based tests.
+func RandomFrame(r *rand.Rand, w, h int, chroma string) (*Frame, error) {
+	f, err := NewFrame(w, h, chroma)
+	if err != nil {
+		return nil, err
+	}
+	r.Read(f.Y)
+	r.Read(f.Cb)
+	r.Read(f.Cr)
+	r.Read(f.Alpha)
+	return f, nil
+}
+
+// RandomDimensions returns a random width and height, each between 1 and max inclusive, that
+// are multiples of the subsampling factors of the given chroma format. A max smaller than a
+// subsampling factor is raised to it, so that there is always a size to return, and the error
+// wraps ErrUnsupportedChroma if the chroma format is unknown.
+func RandomDimensions(r *rand.Rand, chroma string, max int) (w, h int, err error) {
+	xss, yss, err := subsampling(chroma)
+	if err != nil {
+		return 0, 0, err
+	}
+	w = xss * (1 + r.Intn(maxInt(max, xss)/xss))
+	h = yss * (1 + r.Intn(maxInt(max, yss)/yss))
+	return w, h, nil
+}
+
+// WriteRandomStream creates a named stream file of n random frames with width w, height h
+// and the given chroma format, drawn from a NewNoiseSource, and returns the frames that were
+// written so that they can be compared against the frames parsed back from the file.
+func WriteRandomStream(name string, r *rand.Rand, w, h, n int, chroma string) ([]*Frame, error) {
+	src, err := NewNoiseSource(r, w, h, chroma, n)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewStream(name, w, h)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	s.SetChroma(chroma)
+	s.Interlacing = "p"
+	s.FrameRate = &Ratio{25, 1}
+	s.SampleAspectRatio = &Ratio{1, 1}
+	err = s.WriteHeader()
+	if err != nil {
+		return nil, err
+	}
+	rec := &recorder{src: src}
+	_, err = s.WriteSource(rec)
+	if err != nil {
+		return nil, err
+	}
+	return rec.frames, s.Sync()
+}
+
+// recorder is a FrameSource that keeps the frames it passes on from src.
+type recorder struct {
+	src    FrameSource
+	frames []*Frame
+}
+
+func (r *recorder) Next() (*Frame, error) {
+	f, err := r.src.Next()
+	if err == nil {
+		r.frames = append(r.frames, f)
+	}
+	return f, err
+}
@@ -0,0 +1,42 @@
+package y4m
+
+import (
+	"io"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// TestRandomStreamRoundTrip writes a random stream in each chroma format and checks that every
+// frame parsed back from the file equals the frame that was written.
+func TestRandomStreamRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(790))
+	for _, chroma := range RandomChromas {
+		w, h, err := RandomDimensions(r, chroma, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(t.TempDir(), chroma+".y4m")
+		frames, err := WriteRandomStream(name, r, w, h, 3, chroma)
+		if err != nil {
+			t.Fatalf("%s: %v", chroma, err)
+		}
+		s, err := Open(name)
+		if err != nil {
+			t.Fatalf("%s: %v", chroma, err)
+		}
+		for n, want := range frames {
+			got, err := s.ParseFrame()
+			if err != nil {
+				t.Fatalf("%s %dx%d frame %d: %v", chroma, w, h, n, err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("%s %dx%d frame %d differs from the frame written", chroma, w, h, n)
+			}
+		}
+		if _, err := s.ParseFrame(); err != io.EOF {
+			t.Errorf("%s: got %v after the last frame, want io.EOF", chroma, err)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,111 @@
+package y4m
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ratioNames are the frame rates that ParseRatio accepts by name, as FFmpeg does.
+var ratioNames = map[string]Ratio{
+	"ntsc":      {N: 30000, D: 1001},
+	"ntsc-film": {N: 24000, D: 1001},
+	"pal":       {N: 25, D: 1},
+	"film":      {N: 24, D: 1},
+}
+
+// ParseRatio parses a frame rate or aspect ratio given by a user: a ratio "N:D" or "N/D", as
+// written in stream headers, an integer such as "25", a decimal such as "12.5", or one of the
+// names "ntsc", "ntsc-film", "pal" and "film". Decimals within 0.005 of an NTSC rate, a whole
+// number of frames per second slowed by 1000/1001, give that rate exactly: "29.97" and "23.976"
+// give 30000:1001 and 24000:1001. The ratio "0:0" denotes an unknown value.
+func ParseRatio(s string) (*Ratio, error) {
+	if r, ok := ratioNames[strings.ToLower(s)]; ok {
+		return &r, nil
+	}
+	if i := strings.IndexAny(s, ":/"); i >= 0 {
+		n, err1 := strconv.Atoi(s[:i])
+		d, err2 := strconv.Atoi(s[i+1:])
+		r := &Ratio{N: n, D: d}
+		if err1 != nil || err2 != nil || !r.valid() {
+			return nil, fmt.Errorf("could not parse ratio %q", s)
+		}
+		return r, nil
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	n, err := strconv.Atoi(whole + frac)
+	if err != nil || n <= 0 || strings.ContainsAny(s, "+-") || len(frac) > 9 {
+		return nil, fmt.Errorf("could not parse ratio %q", s)
+	}
+	d := int(math.Pow10(len(frac)))
+	if x := float64(n) / float64(d); n%d != 0 {
+		if m := math.Round(x * 1.001); math.Abs(x-m/1.001) < 0.005 {
+			return &Ratio{N: int(m) * 1000, D: 1001}, nil
+		}
+	}
+	return (&Ratio{N: n, D: d}).Reduce(), nil
+}
+
+// known reports whether the ratio has a value, rather than a zero denominator.
+func (r *Ratio) known() bool {
+	return r != nil && r.D != 0
+}
+
+// Reduce returns the ratio in lowest terms with a positive denominator. An unknown ratio, with
+// a zero denominator, is returned as 0:0.
+func (r *Ratio) Reduce() *Ratio {
+	if !r.known() {
+		return &Ratio{N: 0, D: 0}
+	}
+	n, d := r.N, r.D
+	if d < 0 {
+		n, d = -n, -d
+	}
+	g := gcd(absInt(n), d)
+	return &Ratio{N: n / g, D: d / g}
+}
+
+// Float64 returns the value of the ratio, or 0 if it is unknown.
+func (r *Ratio) Float64() float64 {
+	if !r.known() {
+		return 0
+	}
+	return float64(r.N) / float64(r.D)
+}
+
+// Cmp compares the values of ratios r and q, returning -1, 0 or +1 as r is less than, equal
+// to or greater than q, so that 50:2 and 25:1 are equal. Unknown ratios are equal to each
+// other and less than any known ratio.
+func (r *Ratio) Cmp(q *Ratio) int {
+	switch {
+	case !r.known() && !q.known():
+		return 0
+	case !r.known():
+		return -1
+	case !q.known():
+		return 1
+	}
+	return big.NewRat(int64(r.N), int64(r.D)).Cmp(big.NewRat(int64(q.N), int64(q.D)))
+}
+
+// Mul returns the product of ratios r and q in lowest terms, or 0:0 if either is unknown.
+func (r *Ratio) Mul(q *Ratio) *Ratio {
+	if !r.known() || !q.known() {
+		return &Ratio{N: 0, D: 0}
+	}
+	a, b := r.Reduce(), q.Reduce()
+	// Cancel common factors across the terms before multiplying, to keep the products small
+	g1, g2 := gcd(absInt(a.N), b.D), gcd(absInt(b.N), a.D)
+	return (&Ratio{N: (a.N / g1) * (b.N / g2), D: (a.D / g2) * (b.D / g1)}).Reduce()
+}
+
+// Div returns the quotient of ratios r and q in lowest terms, or 0:0 if either is unknown or
+// q is zero.
+func (r *Ratio) Div(q *Ratio) *Ratio {
+	if !q.known() || q.N == 0 {
+		return &Ratio{N: 0, D: 0}
+	}
+	return r.Mul(&Ratio{N: q.D, D: q.N})
+}
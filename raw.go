@@ -0,0 +1,168 @@
+package y4m
+
+import (
+	"fmt"
+	"io"
+)
+
+// RawLayout is an arrangement of the samples of a frame written by Frame.WriteRaw without any
+// header, as expected by libraries and tools that take bare YUV buffers.
+type RawLayout int
+
+// Raw layouts. The planar layouts hold the Y plane followed by the Cb and Cr planes, each
+// without row padding. The packed layouts interleave the samples of each pair of pixels, which
+// share a Cb and a Cr sample, as capture cards and SDI recorders commonly produce.
+const (
+	I420 RawLayout = iota // planar, chroma subsampled horizontally and vertically
+	I422                  // planar, chroma subsampled horizontally
+	I444                  // planar, chroma at full resolution
+	YUY2                  // packed 4:2:2 as Y0 Cb Y1 Cr
+	UYVY                  // packed 4:2:2 as Cb Y0 Cr Y1
+)
+
+func (l RawLayout) String() string {
+	switch l {
+	case I420:
+		return "I420"
+	case I422:
+		return "I422"
+	case I444:
+		return "I444"
+	case YUY2:
+		return "YUY2"
+	case UYVY:
+		return "UYVY"
+	}
+	return fmt.Sprintf("RawLayout(%d)", int(l))
+}
+
+// chroma returns the chroma format whose planes are arranged as the layout requires.
+func (l RawLayout) chroma() (string, error) {
+	switch l {
+	case I420:
+		return "420jpeg", nil
+	case I422, YUY2, UYVY:
+		return "422", nil
+	case I444:
+		return "444", nil
+	}
+	return "", fmt.Errorf("unsupported raw layout %v", l)
+}
+
+// checkWidth checks that frames of width w can be arranged in the layout. The packed layouts
+// hold whole pairs of pixels, so they require an even width.
+func (l RawLayout) checkWidth(w int) error {
+	if (l == YUY2 || l == UYVY) && w%2 != 0 {
+		return fmt.Errorf("%v requires an even width, not %d", l, w)
+	}
+	return nil
+}
+
+// RawSize returns the number of octets written by WriteRaw for a frame of width w and height
+// h in the given layout.
+func RawSize(w, h int, layout RawLayout) (int, error) {
+	chroma, err := layout.chroma()
+	if err != nil {
+		return 0, err
+	}
+	err = layout.checkWidth(w)
+	if err != nil {
+		return 0, err
+	}
+	luma, chromaSize, _ := planeSizes(w, h, chroma)
+	return luma + 2*chromaSize, nil
+}
+
+// WriteRaw writes the samples of the frame to w in the given layout. A frame whose chroma
+// format has different subsampling is converted as by ConvertChroma; the chroma siting of
+// 4:2:0 formats is not changed, and an alpha plane is not written.
+func (f *Frame) WriteRaw(w io.Writer, layout RawLayout) error {
+	chroma, err := layout.chroma()
+	if err != nil {
+		return err
+	}
+	err = layout.checkWidth(f.Width)
+	if err != nil {
+		return err
+	}
+	g := f
+	if f.Chroma == "mono" || xSubsamplingFactor[f.Chroma] != xSubsamplingFactor[chroma] ||
+		ySubsamplingFactor[f.Chroma] != ySubsamplingFactor[chroma] {
+		g, err = f.ConvertChroma(chroma)
+		if err != nil {
+			return err
+		}
+	}
+	if layout == YUY2 || layout == UYVY {
+		_, err = w.Write(packYUV(g, layout))
+		return err
+	}
+	for _, p := range [][]byte{g.Y, g.Cb, g.Cr} {
+		_, err = w.Write(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FrameFromRaw returns a frame of width w and height h holding the samples of b, which are
+// arranged in the given layout. Frames from the packed layouts have 4:2:2 chroma and an even
+// width.
+func FrameFromRaw(b []byte, w, h int, layout RawLayout) (*Frame, error) {
+	chroma, err := layout.chroma()
+	if err != nil {
+		return nil, err
+	}
+	size, err := RawSize(w, h, layout)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("%v frame of size %dx%d has %d octets, not %d", layout, w, h, len(b), size)
+	}
+	f, err := NewFrame(w, h, chroma)
+	if err != nil {
+		return nil, err
+	}
+	if layout == YUY2 || layout == UYVY {
+		unpackYUV(f, b, layout)
+		return f, nil
+	}
+	n := copy(f.Y, b)
+	n += copy(f.Cb, b[n:])
+	copy(f.Cr, b[n:])
+	return f, nil
+}
+
+// packYUV returns the samples of 4:2:2 frame f in packed layout YUY2 or UYVY.
+func packYUV(f *Frame, layout RawLayout) []byte {
+	y, cb, cr := 0, 1, 3
+	if layout == UYVY {
+		y, cb, cr = 1, 0, 2
+	}
+	b := make([]byte, 2*f.Width*f.Height)
+	for k := 0; k < f.Width*f.Height/2; k++ {
+		p := b[4*k : 4*k+4]
+		p[y] = f.Y[2*k]
+		p[y+2] = f.Y[2*k+1]
+		p[cb] = f.Cb[k]
+		p[cr] = f.Cr[k]
+	}
+	return b
+}
+
+// unpackYUV sets the planes of 4:2:2 frame f from b, in packed layout YUY2 or UYVY.
+func unpackYUV(f *Frame, b []byte, layout RawLayout) {
+	y, cb, cr := 0, 1, 3
+	if layout == UYVY {
+		y, cb, cr = 1, 0, 2
+	}
+	for k := 0; k < f.Width*f.Height/2; k++ {
+		p := b[4*k : 4*k+4]
+		f.Y[2*k] = p[y]
+		f.Y[2*k+1] = p[y+2]
+		f.Cb[k] = p[cb]
+		f.Cr[k] = p[cr]
+	}
+}
@@ -0,0 +1,156 @@
+package y4m
+
+import (
+	"bytes"
+	"io"
+)
+
+// Reader reads frames from a stream with its own read offset, independent of the stream's
+// file offset and of other readers. Several readers created from the same stream can be used
+// concurrently from different goroutines, sharing the parsed stream header and the open file.
+// A single Reader must not be used concurrently.
+type Reader struct {
+	s          *Stream
+	pos        int64
+	frameIndex int
+}
+
+// NewReader returns a Reader positioned at the first frame of the stream. The stream must be
+// seekable; a Reader of a stream that is not returns ErrNotSeekable.
+func (s *Stream) NewReader() *Reader {
+	return &Reader{s: s, pos: s.headerLen}
+}
+
+// Offset returns the byte offset of the reader in the stream file.
+func (r *Reader) Offset() int64 {
+	return r.pos
+}
+
+// SeekFrame positions the reader at the beginning of frame n, counting from zero, using the
+// offsets recorded in idx.
+func (r *Reader) SeekFrame(idx FrameIndex, n int) error {
+	if n < 0 || n >= len(idx) {
+		return errFrameOutOfRange(n, len(idx))
+	}
+	r.pos = idx[n]
+	r.frameIndex = n
+	return nil
+}
+
+// ParseFrame parses frame header and planar image data at the reader's offset and returns a
+// Frame. If the stream is memory mapped, the frame's planes are slices of the mapping.
+func (r *Reader) ParseFrame() (*Frame, error) {
+	offset := r.pos
+	h, n, err := r.readFrameHeader()
+	if err != nil {
+		return nil, newFrameError(r.frameIndex, offset, err)
+	}
+	s := r.s
+	pos := offset + int64(n)
+	frame := &Frame{Header: h, Width: s.Width, Height: s.Height, Chroma: s.Chroma}
+	planes := []*[]byte{&frame.Y, &frame.Cb, &frame.Cr, &frame.Alpha}
+	sizes := []int{s.LumaPlaneSize(), s.ChromaPlaneSize(), s.ChromaPlaneSize(), s.AlphaPlaneSize()}
+	for k, size := range sizes {
+		if size == 0 {
+			continue
+		}
+		if s.mapping != nil {
+			if pos+int64(size) > int64(len(s.mapping)) {
+				return nil, newFrameError(r.frameIndex, offset, ErrTruncatedFrame)
+			}
+			*planes[k] = s.mapping[pos : pos+int64(size) : pos+int64(size)]
+		} else {
+			p := make([]byte, size)
+			_, err := s.file.ReadAt(p, pos)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// The frame header has been read, so the file ending here truncates the frame
+				return nil, newFrameError(r.frameIndex, offset, ErrTruncatedFrame)
+			} else if err != nil {
+				return nil, newFrameError(r.frameIndex, offset, err)
+			}
+			*planes[k] = p
+		}
+		pos += int64(size)
+	}
+	r.pos = pos
+	r.frameIndex++
+	return frame, nil
+}
+
+// SkipFrame advances the reader past the next frame without reading its planar data.
+func (r *Reader) SkipFrame() error {
+	offset := r.pos
+	_, n, err := r.readFrameHeader()
+	if err != nil {
+		return newFrameError(r.frameIndex, offset, err)
+	}
+	r.pos = offset + int64(n) + r.s.FrameImageDataSize()
+	r.frameIndex++
+	return nil
+}
+
+// NextFrameHeader parses the header of the frame at the reader's offset and advances the
+// reader past the frame without reading its planar data.
+func (r *Reader) NextFrameHeader() (*FrameHeader, error) {
+	offset := r.pos
+	h, n, err := r.readFrameHeader()
+	if err != nil {
+		return nil, newFrameError(r.frameIndex, offset, err)
+	}
+	r.pos = offset + int64(n) + r.s.FrameImageDataSize()
+	r.frameIndex++
+	return h, nil
+}
+
+// readFrameHeader reads and parses the frame header at the reader's offset, returning the
+// header and its length in octets.
+func (r *Reader) readFrameHeader() (*FrameHeader, int, error) {
+	if r.s.in != nil {
+		return nil, 0, ErrNotSeekable
+	}
+	var hs []byte
+	max := r.s.Limits.MaxHeaderLength
+	if m := r.s.mapping; m != nil {
+		if r.pos >= int64(len(m)) {
+			return nil, 0, io.EOF
+		}
+		rest := m[r.pos:]
+		if max > 0 && len(rest) > max {
+			rest = rest[:max]
+		}
+		n := bytes.IndexByte(rest, '\n')
+		if n < 0 && len(rest) < len(m[r.pos:]) {
+			return nil, 0, headerTooLong(max)
+		} else if n < 0 {
+			return nil, 0, ErrTruncatedFrame
+		}
+		hs = m[r.pos : r.pos+int64(n)+1 : r.pos+int64(n)+1]
+	} else {
+		buf := make([]byte, 64)
+		read := 0
+		for {
+			n, err := r.s.file.ReadAt(buf[read:], r.pos+int64(read))
+			if i := bytes.IndexByte(buf[read:read+n], '\n'); i >= 0 {
+				hs = buf[:read+i+1]
+				break
+			}
+			read += n
+			if max > 0 && read >= max {
+				return nil, 0, headerTooLong(max)
+			}
+			if err == io.EOF && read == 0 {
+				return nil, 0, io.EOF
+			} else if err == io.EOF {
+				return nil, 0, ErrTruncatedFrame
+			} else if err != nil {
+				return nil, 0, err
+			}
+			buf = append(buf, make([]byte, len(buf))...)
+		}
+	}
+	h, err := r.s.parseFrameLine(hs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return h, len(hs), nil
+}
@@ -0,0 +1,243 @@
+package y4m
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// RecoveryStats reports the data discarded while recovering from corrupt frames.
+type RecoveryStats struct {
+	Frames int   // number of corrupt frames dropped
+	Bytes  int64 // number of bytes skipped
+}
+
+// parseFrameRecover parses the next frame, dropping corrupt frames and resynchronizing on the
+// following frame header until a valid frame or the end of the stream is reached. A frame is
+// considered corrupt if its header is malformed, or if another frame header begins within its
+// data. Unexpected bytes following an intact frame are skipped.
+func (s *Stream) parseFrameRecover() (*Frame, error) {
+	if s.in != nil {
+		return s.parseFrameRecoverReader()
+	}
+	for {
+		offset, err := s.file.Seek(0, 1)
+		if err != nil {
+			return nil, err
+		}
+		frame, err := s.parseFrame()
+		if err == nil && s.atFrameBoundary() {
+			return frame, nil
+		}
+		if err != nil {
+			if fe := (*FrameError)(nil); !errors.As(err, &fe) {
+				return nil, err
+			}
+		}
+		end, serr := s.file.Seek(0, 1)
+		if serr != nil {
+			return nil, serr
+		}
+		skipped, err := s.resync(offset + 1)
+		next := offset + 1 + skipped
+		if frame != nil && next >= end {
+			// The frame is intact but followed by unexpected bytes, which may contain whole
+			// frames with malformed headers
+			s.Recovered.Bytes += next - end
+			s.Recovered.Frames += int((next - end) / (int64(len("FRAME\n")) + s.FrameImageDataSize()))
+			return frame, nil
+		}
+		if frame != nil {
+			s.frameIndex--
+		}
+		s.Recovered.Frames++
+		s.Recovered.Bytes += next - offset
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseFrameRecoverReader is parseFrameRecover for a stream that cannot seek. Each frame is
+// parsed from the buffer of s.in before it is consumed, so that the frame's data can still be
+// scanned for the next frame header if the frame turns out to be corrupt.
+func (s *Stream) parseFrameRecoverReader() (*Frame, error) {
+	for {
+		offset := s.pos
+		b, err := s.peekFrame()
+		if len(b) == 0 || err != nil && err != io.EOF {
+			return nil, err
+		}
+		r := bytes.NewReader(b)
+		br := bufio.NewReader(r)
+		frame, ferr := s.readFrameFrom(br)
+		n := len(b) - r.Len() - br.Buffered()
+		// The frame must be followed by the next frame header or the end of the stream
+		rest := b[n:]
+		if ferr == nil && (bytes.HasPrefix(rest, []byte("FRAME")) || len(rest) == 0 && err == io.EOF) {
+			s.in.Discard(n)
+			s.pos += int64(n)
+			s.frameIndex++
+			return frame, nil
+		}
+		if ferr != nil {
+			frame = nil
+		}
+		end := offset + int64(n)
+		s.in.Discard(1)
+		s.pos++
+		skipped, err := s.resyncReader()
+		next := offset + 1 + skipped
+		if frame != nil && next >= end {
+			s.Recovered.Bytes += next - end
+			s.Recovered.Frames += int((next - end) / (int64(len("FRAME\n")) + s.FrameImageDataSize()))
+			s.frameIndex++
+			return frame, nil
+		}
+		s.Recovered.Frames++
+		s.Recovered.Bytes += next - offset
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// peekFrame returns the buffered bytes of s.in that hold the next frame, if it is intact, and
+// the start of the header that follows it. The buffer of s.in is first grown to hold a frame
+// with the longest header accepted. Fewer bytes are returned, with an error, at the end of the
+// stream.
+func (s *Stream) peekFrame() ([]byte, error) {
+	max := s.Limits.MaxHeaderLength
+	if max <= 0 {
+		max = DefaultLimits.MaxHeaderLength
+	}
+	size := max + int(s.FrameImageDataSize()) + len("FRAME")
+	if s.in.Size() < size {
+		s.in = bufio.NewReaderSize(s.in, size)
+	}
+	// Find the end of the frame header line
+	for k := 64; ; k *= 2 {
+		b, err := s.in.Peek(minInt(k, max))
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			return s.in.Peek(i + 1 + int(s.FrameImageDataSize()) + len("FRAME"))
+		} else if err != nil || len(b) >= max {
+			return b, err
+		}
+	}
+}
+
+// atFrameBoundary reports whether the read offset is at the start of a frame header or at the
+// end of the stream, leaving the offset unchanged.
+func (s *Stream) atFrameBoundary() bool {
+	b := make([]byte, len("FRAME"))
+	n, err := io.ReadFull(s.file, b)
+	_, serr := s.file.Seek(-int64(n), 1)
+	if serr != nil {
+		return false
+	}
+	if err == io.EOF {
+		return true
+	}
+	return err == nil && string(b) == "FRAME"
+}
+
+// Resync scans forward from the read offset for the next frame header, a "FRAME" marker
+// followed by a space or newline, and sets the read offset to its start. It returns the number
+// of bytes skipped. If no further frame header exists, the read offset is left at the end of
+// the stream and io.EOF is returned. On a stream that cannot seek, the skipped bytes are read
+// and discarded.
+func (s *Stream) Resync() (int64, error) {
+	if s.in != nil {
+		return s.resyncReader()
+	}
+	offset, err := s.file.Seek(0, 1)
+	if err != nil {
+		return 0, err
+	}
+	return s.resync(offset)
+}
+
+// resyncReader scans for the next frame header of a stream that cannot seek, discarding the
+// bytes before it.
+func (s *Stream) resyncReader() (int64, error) {
+	marker := []byte("FRAME")
+	var skipped int64
+	for {
+		b, err := s.in.Peek(s.in.Size())
+		// Bytes that cannot begin a frame header and can be discarded
+		n := len(b)
+		for k := 0; ; {
+			i := bytes.Index(b[k:], marker)
+			if i < 0 {
+				if err == nil {
+					// Keep the bytes that may begin a marker completed by the next read
+					n = len(b) - len(marker)
+				}
+				break
+			}
+			i += k
+			if i+len(marker) == len(b) {
+				if err == nil {
+					n = i
+				}
+				break
+			}
+			if c := b[i+len(marker)]; c == ' ' || c == '\n' {
+				s.in.Discard(i)
+				s.pos += int64(i)
+				return skipped + int64(i), nil
+			}
+			k = i + 1
+		}
+		s.in.Discard(n)
+		s.pos += int64(n)
+		skipped += int64(n)
+		if err != nil {
+			return skipped, err
+		}
+	}
+}
+
+// resync scans for the next frame header from offset.
+func (s *Stream) resync(offset int64) (int64, error) {
+	_, err := s.file.Seek(offset, 0)
+	if err != nil {
+		return 0, err
+	}
+	const chunkSize = 64 * 1024
+	marker := []byte("FRAME")
+	buf := make([]byte, chunkSize)
+	// carry holds the bytes from the end of the previous chunk that may begin a marker
+	carry := 0
+	pos := offset
+	for {
+		n, err := io.ReadFull(s.file, buf[carry:])
+		data := buf[:carry+n]
+		for k := 0; ; {
+			i := bytes.Index(data[k:], marker)
+			if i < 0 {
+				break
+			}
+			i += k
+			if i+len(marker) >= len(data) && err == nil {
+				// Need the following byte to confirm the marker; rescan with the next chunk
+				break
+			}
+			if i+len(marker) < len(data) && (data[i+len(marker)] == ' ' || data[i+len(marker)] == '\n') {
+				_, serr := s.file.Seek(pos+int64(i), 0)
+				return pos + int64(i) - offset, serr
+			}
+			k = i + 1
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			end := pos + int64(len(data))
+			return end - offset, io.EOF
+		} else if err != nil {
+			return pos + int64(len(data)) - offset, err
+		}
+		carry = len(marker)
+		copy(buf, data[len(data)-carry:])
+		pos += int64(len(data) - carry)
+	}
+}
@@ -0,0 +1,183 @@
+package y4m
+
+import (
+	"fmt"
+	"math"
+)
+
+// Kernel selects the interpolation filter used by Resize.
+type Kernel int
+
+// Resampling kernels, in increasing order of quality and cost.
+const (
+	// Nearest takes the nearest source sample.
+	Nearest Kernel = iota
+	// Bilinear interpolates linearly between the two nearest source samples.
+	Bilinear
+	// Bicubic uses the Catmull-Rom cubic spline over four source samples.
+	Bicubic
+	// Lanczos3 uses a three lobe Lanczos windowed sinc over six source samples.
+	Lanczos3
+)
+
+var kernelNames = map[Kernel]string{
+	Nearest:  "nearest",
+	Bilinear: "bilinear",
+	Bicubic:  "bicubic",
+	Lanczos3: "lanczos",
+}
+
+// String returns the name of the kernel.
+func (k Kernel) String() string {
+	if n, ok := kernelNames[k]; ok {
+		return n
+	}
+	return fmt.Sprintf("Kernel(%d)", int(k))
+}
+
+// ParseKernel returns the kernel with the given name: "nearest", "bilinear", "bicubic" or
+// "lanczos".
+func ParseKernel(name string) (Kernel, error) {
+	for k, n := range kernelNames {
+		if n == name {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognized kernel %q", name)
+}
+
+// support returns the radius of the kernel and its weight function.
+func (k Kernel) support() (float64, func(float64) float64) {
+	switch k {
+	case Bilinear:
+		return 1, func(x float64) float64 { return 1 - math.Abs(x) }
+	case Bicubic:
+		return 2, func(x float64) float64 {
+			x = math.Abs(x)
+			if x < 1 {
+				return 1.5*x*x*x - 2.5*x*x + 1
+			}
+			return -0.5*x*x*x + 2.5*x*x - 4*x + 2
+		}
+	case Lanczos3:
+		return 3, func(x float64) float64 {
+			if x == 0 {
+				return 1
+			}
+			px := math.Pi * x
+			return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+		}
+	}
+	return 0.5, func(float64) float64 { return 1 }
+}
+
+// Resize scales the frame image to width w and height h using kernel k. When reducing, the
+// kernel is widened to avoid aliasing. Chroma and alpha planes are scaled with the luma
+// plane. The frame's w and h fields are updated.
+func (f *Frame) Resize(w, h int, k Kernel) error {
+	err := checkGeometry(w, h, f.Chroma)
+	if err != nil {
+		return err
+	}
+	xss, yss, err := subsampling(f.Chroma)
+	if err != nil {
+		return err
+	}
+	f.Y = resizePlane(f.Plane(PlaneY), w, h, k)
+	if len(f.Cb) > 0 {
+		f.Cb = resizePlane(f.Plane(PlaneCb), w/xss, h/yss, k)
+		f.Cr = resizePlane(f.Plane(PlaneCr), w/xss, h/yss, k)
+	}
+	if len(f.Alpha) > 0 {
+		f.Alpha = resizePlane(f.Plane(PlaneAlpha), w, h, k)
+	}
+	f.Width = w
+	f.Height = h
+	return nil
+}
+
+// Resize updates the stream geometry to match frames resized to width w and height h. A known
+// sample aspect ratio is adjusted so that the display aspect ratio is unchanged. It should be
+// called on an output stream before its header is written.
+func (s *Stream) Resize(w, h int) {
+	dar := s.DisplayAspectRatio()
+	s.Width = w
+	s.Height = h
+	if dar != nil {
+		s.SetDisplayAspectRatio(dar)
+	}
+}
+
+// taps holds the source samples and weights contributing to each destination sample along
+// one axis.
+type taps struct {
+	first   []int       // index of the first source sample of each destination sample
+	weights [][]float32 // weights of consecutive source samples, starting from first
+}
+
+// newTaps computes the taps for resampling n source samples to m destination samples.
+func newTaps(n, m int, k Kernel) taps {
+	radius, weight := k.support()
+	scale := math.Max(float64(n)/float64(m), 1)
+	t := taps{first: make([]int, m), weights: make([][]float32, m)}
+	for i := 0; i < m; i++ {
+		centre := (float64(i)+0.5)*float64(n)/float64(m) - 0.5
+		if k == Nearest {
+			t.first[i] = minInt(int(centre+0.5), n-1)
+			t.weights[i] = []float32{1}
+			continue
+		}
+		lo := int(math.Floor(centre-radius*scale)) + 1
+		hi := int(math.Ceil(centre + radius*scale))
+		ws := make([]float64, 0, hi-lo)
+		var sum float64
+		for j := lo; j < hi; j++ {
+			v := weight((float64(j) - centre) / scale)
+			ws = append(ws, v)
+			sum += v
+		}
+		t.first[i] = lo
+		t.weights[i] = make([]float32, len(ws))
+		for j, v := range ws {
+			t.weights[i][j] = float32(v / sum)
+		}
+	}
+	return t
+}
+
+// resizePlane returns plane p resampled to width w and height h. Source samples beyond the
+// edges of the plane repeat the edge samples.
+func resizePlane(p Plane, w, h int, k Kernel) []byte {
+	tx, ty := newTaps(p.Width, w, k), newTaps(p.Height, h, k)
+	clamp := func(i, n int) int { return maxInt(0, minInt(i, n-1)) }
+	// Resample rows, then columns
+	tmp := make([]float32, w*p.Height)
+	for y := 0; y < p.Height; y++ {
+		row := p.Row(y)
+		for x := 0; x < w; x++ {
+			var v float32
+			for j, wt := range tx.weights[x] {
+				v += wt * float32(row[clamp(tx.first[x]+j, p.Width)])
+			}
+			tmp[y*w+x] = v
+		}
+	}
+	out := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float32
+			for j, wt := range ty.weights[y] {
+				v += wt * tmp[clamp(ty.first[y]+j, p.Height)*w+x]
+			}
+			out[y*w+x] = clampByte(int(v + 0.5))
+		}
+	}
+	return out
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,106 @@
+package y4m
+
+import (
+	"fmt"
+)
+
+// RetimeMode selects how a Retimer converts between frame rates.
+type RetimeMode int
+
+const (
+	// RetimeNearest drops or duplicates frames, taking for each output frame the input frame
+	// nearest to it in time.
+	RetimeNearest RetimeMode = iota
+	// RetimeBlend blends the two input frames either side of each output frame, weighted by
+	// their distance from it in time.
+	RetimeBlend
+)
+
+// Retimer converts a sequence of frames from one frame rate to another, for example to
+// conform content between 24, 25, 30 and 60 frames per second.
+type Retimer struct {
+	mode RetimeMode
+	a, b int64  // input frames advance by a/b per output frame
+	in   int64  // number of input frames pushed
+	out  int64  // number of output frames returned
+	prev *Frame // last input frame pushed
+}
+
+// NewRetimer returns a Retimer converting frames at rate from into frames at rate to.
+func NewRetimer(from, to *Ratio, mode RetimeMode) (*Retimer, error) {
+	if from == nil || to == nil || from.N <= 0 || from.D <= 0 || to.N <= 0 || to.D <= 0 {
+		return nil, fmt.Errorf("cannot retime from %v to %v; frame rates must be known", from, to)
+	}
+	return &Retimer{
+		mode: mode,
+		a:    int64(from.N) * int64(to.D),
+		b:    int64(to.N) * int64(from.D),
+	}, nil
+}
+
+// Push adds the next input frame and returns the output frames that are now complete. A
+// frame that is duplicated is returned more than once. Blended frames take a copy of the
+// header of the earlier input frame.
+func (r *Retimer) Push(f *Frame) ([]*Frame, error) {
+	if r.prev != nil {
+		err := checkComparable(r.prev, f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var frames []*Frame
+	n := r.in
+	r.in++
+	switch r.mode {
+	case RetimeNearest:
+		// Output frame m shows input frame round(m*a/b)
+		for (2*r.out*r.a+r.b)/(2*r.b) <= n {
+			frames = append(frames, f)
+			r.out++
+		}
+	case RetimeBlend:
+		// Output frame m lies between input frames floor(m*a/b) and the next
+		for r.prev != nil && r.out*r.a/r.b == n-1 {
+			frames = append(frames, blendFrames(r.prev, f, r.out*r.a%r.b, r.b))
+			r.out++
+		}
+	default:
+		return nil, fmt.Errorf("unknown retime mode %d", r.mode)
+	}
+	r.prev = f
+	return frames, nil
+}
+
+// Flush returns the output frames due before the end of the last input frame that have not
+// yet been returned, which repeat the last input frame.
+func (r *Retimer) Flush() []*Frame {
+	var frames []*Frame
+	for r.prev != nil && r.out*r.a < r.in*r.b {
+		frames = append(frames, r.prev)
+		r.out++
+	}
+	return frames
+}
+
+// blendFrames returns a frame whose samples are those of f and g weighted by 1-n/d and n/d.
+func blendFrames(f, g *Frame, n, d int64) *Frame {
+	if n == 0 {
+		return f
+	}
+	out := &Frame{Header: f.Header.Copy(), Width: f.Width, Height: f.Height, Chroma: f.Chroma}
+	mix := func(a, b []byte) []byte {
+		if a == nil {
+			return nil
+		}
+		p := make([]byte, len(a))
+		for k := range a {
+			p[k] = byte((int64(a[k])*(d-n) + int64(b[k])*n + d/2) / d)
+		}
+		return p
+	}
+	out.Y = mix(f.Y, g.Y)
+	out.Cb = mix(f.Cb, g.Cb)
+	out.Cr = mix(f.Cr, g.Cr)
+	out.Alpha = mix(f.Alpha, g.Alpha)
+	return out
+}
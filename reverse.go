@@ -0,0 +1,27 @@
+package y4m
+
+// ReverseStream writes the frames of stream in to stream out in reverse order. Frames are
+// located using a frame index and read one at a time, so the input is never held in memory.
+// The header of out should be written beforehand. Note that reversing interlaced content also
+// reverses the display order of each frame's fields.
+func ReverseStream(in, out *Stream) error {
+	idx, err := in.BuildIndex()
+	if err != nil {
+		return err
+	}
+	for n := len(idx) - 1; n >= 0; n-- {
+		err = in.SeekFrame(idx, n)
+		if err != nil {
+			return err
+		}
+		f, err := in.ParseFrame()
+		if err != nil {
+			return err
+		}
+		err = out.WriteFrame(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package y4m
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Matrix is the set of coefficients used to convert between Y'CbCr and R'G'B'.
+type Matrix int
+
+// Matrices of ITU-R recommendations. BT.601 is the matrix assumed by the standard library's
+// color conversions; BT.709 is used by HD video, and BT.2020 by UHD and HDR video.
+const (
+	BT601 Matrix = iota
+	BT709
+	BT2020
+)
+
+func (m Matrix) String() string {
+	switch m {
+	case BT601:
+		return "BT.601"
+	case BT709:
+		return "BT.709"
+	case BT2020:
+		return "BT.2020"
+	}
+	return fmt.Sprintf("Matrix(%d)", int(m))
+}
+
+// coefficients returns the luma weights of red and blue for the matrix.
+func (m Matrix) coefficients() (kr, kb float64, err error) {
+	switch m {
+	case BT601:
+		return 0.299, 0.114, nil
+	case BT709:
+		return 0.2126, 0.0722, nil
+	case BT2020:
+		return 0.2627, 0.0593, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported matrix %v", m)
+}
+
+// ToNRGBA converts the frame to an 8-bit R'G'B' image using matrix m, for samples in range r;
+// an unspecified range is treated as full range. Chroma is upsampled to full resolution by
+// bilinear interpolation, taking the chroma siting of the frame's format into account: 4:2:0
+// JPEG chroma is centred between luma samples, and the chroma of the other subsampled formats
+// is co-sited horizontally with the first luma sample of each pair. Alpha is taken from the
+// alpha plane, or is opaque for frames without one. Unlike Image, the conversion does not go
+// through image.YCbCr, whose color model always uses full range BT.601 and nearest neighbour
+// chroma.
+func (f *Frame) ToNRGBA(m Matrix, r ColorRange) (*image.NRGBA, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, f.Width, f.Height))
+	err := f.toRGB(img.Pix, m, r)
+	if err != nil {
+		return nil, err
+	}
+	for k, a := range f.Alpha {
+		img.Pix[4*k+3] = a
+	}
+	return img, nil
+}
+
+// ToRGBA is like ToNRGBA, but returns an image with alpha-premultiplied color.
+func (f *Frame) ToRGBA(m Matrix, r ColorRange) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+	err := f.toRGB(img.Pix, m, r)
+	if err != nil {
+		return nil, err
+	}
+	for k, a := range f.Alpha {
+		p := img.Pix[4*k : 4*k+4]
+		for i := 0; i < 3; i++ {
+			p[i] = byte((int(p[i])*int(a) + 127) / 255)
+		}
+		p[3] = a
+	}
+	return img, nil
+}
+
+// ToNRGBA64 is like ToNRGBA, but returns an image with 16-bit samples, which keep the precision
+// of the matrix conversion and of the interpolated chroma instead of rounding to 8 bits.
+func (f *Frame) ToNRGBA64(m Matrix, r ColorRange) (*image.NRGBA64, error) {
+	img := image.NewNRGBA64(image.Rect(0, 0, f.Width, f.Height))
+	err := f.eachRGB(m, r, func(k int, red, green, blue float64) {
+		p := img.Pix[8*k : 8*k+8]
+		for i, v := range []float64{red, green, blue, 1} {
+			u := unitToUint16(v)
+			p[2*i], p[2*i+1] = byte(u>>8), byte(u)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for k, a := range f.Alpha {
+		img.Pix[8*k+6], img.Pix[8*k+7] = a, a
+	}
+	return img, nil
+}
+
+// ToGray16 returns the luma of the frame as a 16-bit image, for samples in range r; an
+// unspecified range is treated as full range. Limited range samples are expanded to the full
+// 16-bit range without rounding to 8 bits.
+func (f *Frame) ToGray16(r ColorRange) *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, f.Width, f.Height))
+	for k, v := range f.Y {
+		u := uint16(v) * 0x101
+		if r == ColorRangeLimited {
+			u = unitToUint16((float64(v) - 16) / 219)
+		}
+		img.Pix[2*k], img.Pix[2*k+1] = byte(u>>8), byte(u)
+	}
+	return img
+}
+
+// toRGB writes the opaque 8-bit R'G'B'A pixels of the frame to pix.
+func (f *Frame) toRGB(pix []byte, m Matrix, r ColorRange) error {
+	return f.eachRGB(m, r, func(k int, red, green, blue float64) {
+		p := pix[4*k : 4*k+4]
+		p[0], p[1], p[2], p[3] = unitToByte(red), unitToByte(green), unitToByte(blue), 0xff
+	})
+}
+
+// eachRGB converts the pixels of the frame to R'G'B' with matrix m for samples in range r,
+// calling set with the index of each pixel and its unclamped components, nominally in [0, 1].
+func (f *Frame) eachRGB(m Matrix, r ColorRange, set func(k int, red, green, blue float64)) error {
+	kr, kb, err := m.coefficients()
+	if err != nil {
+		return err
+	}
+	if _, ok := xSubsamplingFactor[f.Chroma]; !ok && f.Chroma != "mono" {
+		return fmt.Errorf("%w: %s", ErrUnsupportedChroma, f.Chroma)
+	}
+	// Scale factors from samples to Y' in [0, 1] and Pb, Pr in [-0.5, 0.5]
+	yOffset, yScale, cScale := 0.0, 1/255.0, 1/255.0
+	if r == ColorRangeLimited {
+		yOffset, yScale, cScale = 16, 1/219.0, 1/224.0
+	}
+	crR := 2 * (1 - kr)
+	cbB := 2 * (1 - kb)
+	cbG := -cbB * kb / (1 - kr - kb)
+	crG := -crR * kr / (1 - kr - kb)
+	cb, cr := f.upsampledChroma()
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			k := y*f.Width + x
+			yy := (float64(f.Y[k]) - yOffset) * yScale
+			pb, pr := 0.0, 0.0
+			if cb != nil {
+				pb = (float64(cb[k]) - 128*256) / 256 * cScale
+				pr = (float64(cr[k]) - 128*256) / 256 * cScale
+			}
+			set(k, yy+crR*pr, yy+cbG*pb+crG*pr, yy+cbB*pb)
+		}
+	}
+	return nil
+}
+
+// unitToByte scales v in [0, 1] to a byte, rounding and clamping.
+func unitToByte(v float64) byte {
+	v = math.Round(v * 255)
+	if v < 0 {
+		return 0
+	} else if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
+
+// unitToUint16 scales v in [0, 1] to a 16-bit sample, rounding and clamping.
+func unitToUint16(v float64) uint16 {
+	v = math.Round(v * 0xffff)
+	if v < 0 {
+		return 0
+	} else if v > 0xffff {
+		return 0xffff
+	}
+	return uint16(v)
+}
+
+// upsampledChroma returns the chroma planes of the frame interpolated to full resolution,
+// with 8 fractional bits, or nil planes for a mono frame.
+func (f *Frame) upsampledChroma() (cb, cr []int32) {
+	if f.Chroma == "mono" || len(f.Cb) == 0 {
+		return nil, nil
+	}
+	xss, yss := xSubsamplingFactor[f.Chroma], ySubsamplingFactor[f.Chroma]
+	cw, ch := chromaDims(f.Width, f.Height, f.Chroma)
+	xi, xw := chromaTaps(f.Width, cw, xss, f.Chroma != "420jpeg")
+	yi, yw := chromaTaps(f.Height, ch, yss, false)
+	cb = make([]int32, f.Width*f.Height)
+	cr = make([]int32, f.Width*f.Height)
+	for y := 0; y < f.Height; y++ {
+		r0, r1, wy := yi[y]*cw, minInt(yi[y]+1, ch-1)*cw, int32(yw[y])
+		for x := 0; x < f.Width; x++ {
+			c0, c1, wx := xi[x], minInt(xi[x]+1, cw-1), int32(xw[x])
+			k := y*f.Width + x
+			cb[k] = bilinear(f.Cb, r0, r1, c0, c1, wx, wy)
+			cr[k] = bilinear(f.Cr, r0, r1, c0, c1, wx, wy)
+		}
+	}
+	return cb, cr
+}
+
+// bilinear interpolates between the samples of plane p at columns c0 and c1 of the rows
+// beginning at r0 and r1, with weights wx and wy of the second column and row in 1/256ths.
+// The result has 8 fractional bits.
+func bilinear(p []byte, r0, r1, c0, c1 int, wx, wy int32) int32 {
+	top := int32(p[r0+c0])*(256-wx) + int32(p[r0+c1])*wx
+	bottom := int32(p[r1+c0])*(256-wx) + int32(p[r1+c1])*wx
+	return (top*(256-wy) + bottom*wy + 128) >> 8
+}
+
+// chromaTaps returns, for each of n luma positions, the index of the nearest of the cn chroma
+// samples at or before it and the weight of the following chroma sample in 1/256ths, for a
+// subsampling factor of ss. Chroma samples are centred between the luma samples they cover
+// unless cosited is true, in which case they are sited at the first of them.
+func chromaTaps(n, cn, ss int, cosited bool) ([]int, []int) {
+	idx := make([]int, n)
+	wt := make([]int, n)
+	for x := 0; x < n; x++ {
+		p := float64(x) / float64(ss)
+		if !cosited {
+			p = (float64(2*x+1) - float64(ss)) / float64(2*ss)
+		}
+		i := math.Floor(p)
+		w := p - i
+		if i < 0 {
+			i, w = 0, 0
+		} else if int(i) >= cn-1 {
+			i, w = float64(cn-1), 0
+		}
+		idx[x] = int(i)
+		wt[x] = int(math.Round(w * 256))
+	}
+	return idx, wt
+}
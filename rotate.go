@@ -0,0 +1,87 @@
+package y4m
+
+import (
+	"fmt"
+)
+
+// Rotate rotates the frame image clockwise by degrees, which must be 0, 90, 180 or 270.
+// Rotating by 90 or 270 degrees transposes all planes and swaps the frame's Width and
+// Height fields, so it requires equal horizontal and vertical chroma subsampling.
+func (f *Frame) Rotate(degrees int) error {
+	degrees = normalizeDegrees(degrees)
+	if degrees%90 != 0 {
+		return fmt.Errorf("rotation must be a multiple of 90 degrees, got %d", degrees)
+	}
+	if degrees == 0 {
+		return nil
+	}
+	xss := xSubsamplingFactor[f.Chroma]
+	yss := ySubsamplingFactor[f.Chroma]
+	if degrees != 180 && xss != yss {
+		return fmt.Errorf("cannot rotate %s frame by %d degrees; chroma subsampling is not symmetric",
+			f.Chroma, degrees)
+	}
+	f.Y = rotatePlane(f.Y, f.Width, f.Height, degrees)
+	if len(f.Cb) > 0 {
+		cw, ch := chromaDims(f.Width, f.Height, f.Chroma)
+		f.Cb = rotatePlane(f.Cb, cw, ch, degrees)
+		f.Cr = rotatePlane(f.Cr, cw, ch, degrees)
+	}
+	if len(f.Alpha) > 0 {
+		f.Alpha = rotatePlane(f.Alpha, f.Width, f.Height, degrees)
+	}
+	if degrees != 180 {
+		f.Width, f.Height = f.Height, f.Width
+	}
+	return nil
+}
+
+// Rotate updates the stream geometry to match frames rotated clockwise by degrees. For 90 and
+// 270 degree rotations, Width and Height are swapped and the sample aspect ratio is inverted.
+// It should be called on an output stream before its header is written.
+func (s *Stream) Rotate(degrees int) error {
+	degrees = normalizeDegrees(degrees)
+	if degrees%90 != 0 {
+		return fmt.Errorf("rotation must be a multiple of 90 degrees, got %d", degrees)
+	}
+	if degrees == 0 || degrees == 180 {
+		return nil
+	}
+	if s.XSubsamplingFactor != s.YSubsamplingFactor {
+		return fmt.Errorf("cannot rotate %s stream by %d degrees; chroma subsampling is not symmetric",
+			s.Chroma, degrees)
+	}
+	s.Width, s.Height = s.Height, s.Width
+	if s.SampleAspectRatio != nil {
+		s.SampleAspectRatio = &Ratio{N: s.SampleAspectRatio.D, D: s.SampleAspectRatio.N}
+	}
+	return nil
+}
+
+// normalizeDegrees maps an angle in degrees onto the range [0, 360).
+func normalizeDegrees(degrees int) int {
+	degrees %= 360
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// rotatePlane returns a copy of plane p, of width w and height h, rotated clockwise by degrees.
+func rotatePlane(p []byte, w, h, degrees int) []byte {
+	out := make([]byte, len(p))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := p[y*w+x]
+			switch degrees {
+			case 90:
+				out[x*h+(h-1-y)] = v
+			case 180:
+				out[(h-1-y)*w+(w-1-x)] = v
+			case 270:
+				out[(w-1-x)*h+y] = v
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package y4m
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WritePGM writes the luma plane of the frame to w as a binary PGM image. The samples are
+// written unchanged, whatever their range.
+func (f *Frame) WritePGM(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "P5\n%d %d\n255\n", f.Width, f.Height)
+	bw.Write(f.Y)
+	return bw.Flush()
+}
+
+// WritePPM writes the frame to w as a binary PPM image, converted to R'G'B' as by ToNRGBA with
+// matrix m and range r. The alpha plane is not written.
+func (f *Frame) WritePPM(w io.Writer, m Matrix, r ColorRange) error {
+	img, err := f.ToNRGBA(m, r)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "P6\n%d %d\n255\n", f.Width, f.Height)
+	for k := 0; k < len(img.Pix); k += 4 {
+		bw.Write(img.Pix[k : k+3])
+	}
+	return bw.Flush()
+}
+
+// WritePAM writes the frame to w as a PAM image. Mono frames are written as GRAYSCALE images
+// of their luma samples, and other frames as RGB images converted as by ToNRGBA with matrix m
+// and range r, or as RGB_ALPHA images if they have an alpha plane.
+func (f *Frame) WritePAM(w io.Writer, m Matrix, r ColorRange) error {
+	bw := bufio.NewWriter(w)
+	if f.Chroma == "mono" {
+		fmt.Fprintf(bw, "P7\nWIDTH %d\nHEIGHT %d\nDEPTH 1\nMAXVAL 255\nTUPLTYPE GRAYSCALE\nENDHDR\n",
+			f.Width, f.Height)
+		bw.Write(f.Y)
+		return bw.Flush()
+	}
+	img, err := f.ToNRGBA(m, r)
+	if err != nil {
+		return err
+	}
+	depth, tupleType := 3, "RGB"
+	if len(f.Alpha) > 0 {
+		depth, tupleType = 4, "RGB_ALPHA"
+	}
+	fmt.Fprintf(bw, "P7\nWIDTH %d\nHEIGHT %d\nDEPTH %d\nMAXVAL 255\nTUPLTYPE %s\nENDHDR\n",
+		f.Width, f.Height, depth, tupleType)
+	for k := 0; k < len(img.Pix); k += 4 {
+		bw.Write(img.Pix[k : k+depth])
+	}
+	return bw.Flush()
+}
+
+// WriteY4M writes the frame to w as a stream of one frame. The stream header gives the frame's
+// size and chroma format, progressive interlacing, a frame rate of 1:1 and square pixels.
+func (f *Frame) WriteY4M(w io.Writer) error {
+	s := NewStreamWriter(w, f.Width, f.Height)
+	err := s.SetChroma(f.Chroma)
+	if err != nil {
+		return err
+	}
+	s.Interlacing = "p"
+	s.FrameRate = &Ratio{1, 1}
+	s.SampleAspectRatio = &Ratio{1, 1}
+	err = s.WriteHeader()
+	if err != nil {
+		return err
+	}
+	err = s.WriteFrame(f)
+	if err != nil {
+		return err
+	}
+	return s.Close()
+}
@@ -0,0 +1,135 @@
+package y4m
+
+import (
+	"image/color"
+	"io"
+	"math/rand"
+)
+
+// FrameSource produces a sequence of frames, such as a synthetic test stream. Next returns
+// io.EOF after the last frame. The sources in this package return a new frame from each call,
+// so callers may modify the frames they receive.
+type FrameSource interface {
+	Next() (*Frame, error)
+}
+
+// generator is a FrameSource of n frames, where frame k is returned by frame.
+type generator struct {
+	k, n  int
+	frame func(k int) (*Frame, error)
+}
+
+func (g *generator) Next() (*Frame, error) {
+	if g.k >= g.n {
+		return nil, io.EOF
+	}
+	f, err := g.frame(g.k)
+	if err != nil {
+		return nil, err
+	}
+	g.k++
+	return f, nil
+}
+
+// staticSource returns a source repeating frame f, n times.
+func staticSource(f *Frame, err error, n int) (FrameSource, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &generator{n: n, frame: func(int) (*Frame, error) { return f.Copy(), nil }}, nil
+}
+
+// NewSolidSource returns a source of n frames of width w and height h in the given chroma
+// format, filled with color c.
+func NewSolidSource(w, h int, chroma string, c color.YCbCr, n int) (FrameSource, error) {
+	f, err := patternFrame(w, h, chroma, func(x, y int) [3]byte { return [3]byte{c.Y, c.Cb, c.Cr} })
+	return staticSource(f, err, n)
+}
+
+// NewRampSource returns a source of n frames with a luma ramp from black to white and neutral
+// chroma, running from left to right, or from top to bottom if vertical is true.
+func NewRampSource(w, h int, chroma string, vertical bool, n int) (FrameSource, error) {
+	if !vertical {
+		f, err := Gradient(w, h, chroma)
+		return staticSource(f, err, n)
+	}
+	f, err := patternFrame(w, h, chroma, func(x, y int) [3]byte {
+		if h == 1 {
+			return [3]byte{16, 128, 128}
+		}
+		return [3]byte{byte(16 + roundDiv(219*y, h-1)), 128, 128}
+	})
+	return staticSource(f, err, n)
+}
+
+// NewNoiseSource returns a source of n frames filled with pseudo-random data drawn from r, as
+// generated by RandomFrame.
+func NewNoiseSource(r *rand.Rand, w, h int, chroma string, n int) (FrameSource, error) {
+	err := checkGeometry(w, h, chroma)
+	if err != nil {
+		return nil, err
+	}
+	return &generator{n: n, frame: func(int) (*Frame, error) { return RandomFrame(r, w, h, chroma) }}, nil
+}
+
+// NewShapeSource returns a source of n frames in which a white square and a red disc move
+// across a gray background, bouncing off the edges of the frame. The square moves
+// horizontally and the disc vertically, each by a fixed number of samples per frame, which
+// gives motion estimation and frame rate conversion something predictable to follow.
+func NewShapeSource(w, h int, chroma string, n int) (FrameSource, error) {
+	err := checkGeometry(w, h, chroma)
+	if err != nil {
+		return nil, err
+	}
+	gray, white, red := studio(.25, .25, .25), studio(1, 1, 1), studio(.75, 0, 0)
+	size := maxInt(minInt(w, h)/4, 1)
+	speed := maxInt(minInt(w, h)/32, 1)
+	frame := func(k int) (*Frame, error) {
+		// The square runs along the upper half and the disc along the right half
+		sx, sy := bounce(k*speed, w-size), h/4-size/2
+		dx, dy := w*3/4-size/2, bounce(k*speed, h-size)
+		r2 := size * size
+		return patternFrame(w, h, chroma, func(x, y int) [3]byte {
+			if ex, ey := 2*(x-dx)-size+1, 2*(y-dy)-size+1; ex*ex+ey*ey <= r2 {
+				return red
+			}
+			if x >= sx && x < sx+size && y >= sy && y < sy+size {
+				return white
+			}
+			return gray
+		})
+	}
+	return &generator{n: n, frame: frame}, nil
+}
+
+// bounce returns the position after moving d samples along a track of length span, reversing
+// direction at each end.
+func bounce(d, span int) int {
+	if span <= 0 {
+		return 0
+	}
+	d %= 2 * span
+	if d > span {
+		d = 2*span - d
+	}
+	return d
+}
+
+// WriteSource writes the frames of src to stream s until src is exhausted, and returns the
+// number of frames written. The stream header must already have been written.
+func (s *Stream) WriteSource(src FrameSource) (int, error) {
+	count := 0
+	for {
+		f, err := src.Next()
+		if err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+		err = s.WriteFrame(f)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
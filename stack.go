@@ -0,0 +1,71 @@
+package y4m
+
+import (
+	"fmt"
+)
+
+// Stack places frames side by side from left to right, or one above the other from top to
+// bottom if vertical is true, in a new frame. The frames must share a chroma format and have
+// equal heights, or equal widths if stacked vertically, and their sizes must be multiples of
+// the chroma subsampling. The new frame has a copy of the first frame's header.
+func Stack(vertical bool, frames ...*Frame) (*Frame, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames to stack")
+	}
+	first := frames[0]
+	w, h := 0, 0
+	for _, f := range frames {
+		if f.Chroma != first.Chroma {
+			return nil, fmt.Errorf("cannot stack %s and %s frames", first.Chroma, f.Chroma)
+		}
+		err := checkGeometry(f.Width, f.Height, f.Chroma)
+		if err != nil {
+			return nil, err
+		}
+		if vertical {
+			if f.Width != first.Width {
+				return nil, fmt.Errorf("cannot stack frames of widths %d and %d vertically",
+					first.Width, f.Width)
+			}
+			w, h = f.Width, h+f.Height
+		} else {
+			if f.Height != first.Height {
+				return nil, fmt.Errorf("cannot stack frames of heights %d and %d side by side",
+					first.Height, f.Height)
+			}
+			w, h = w+f.Width, f.Height
+		}
+	}
+	out, err := NewFrame(w, h, first.Chroma)
+	if err != nil {
+		return nil, err
+	}
+	out.Header = first.Header.Copy()
+	xss, yss, err := subsampling(first.Chroma)
+	if err != nil {
+		return nil, err
+	}
+	x, y := 0, 0
+	for _, f := range frames {
+		for i := PlaneY; i <= PlaneAlpha; i++ {
+			src := f.Plane(i)
+			if src.Data == nil {
+				continue
+			}
+			px, py := x, y
+			if i == PlaneCb || i == PlaneCr {
+				px, py = x/xss, y/yss
+			}
+			dst := out.Plane(i).SubPlane(px, py, src.Width, src.Height)
+			for row := 0; row < src.Height; row++ {
+				copy(dst.Row(row), src.Row(row))
+			}
+		}
+		if vertical {
+			y += f.Height
+		} else {
+			x += f.Width
+		}
+	}
+	return out, nil
+}
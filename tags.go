@@ -0,0 +1,72 @@
+package y4m
+
+import "strconv"
+
+// parseTag stores the value of stream header X tag t in the corresponding typed stream field
+// if it is a well-known tag with a recognized value, and reports whether it did so.
+func (s *Stream) parseTag(t string) bool {
+	key, value := splitMetadata(t)
+	switch key {
+	case "YSCSS":
+		s.YSCSS = value
+		return true
+	case "COLORRANGE":
+		for r, n := range colorRangeNames {
+			if n == value {
+				s.ColorRange = r
+				return true
+			}
+		}
+	case "MASTERING_DISPLAY":
+		m, err := ParseMasteringDisplay(value)
+		if err == nil {
+			s.MasteringDisplay = m
+			return true
+		}
+	case "MAXCLL":
+		if n, ok := parseLightLevel(value); ok {
+			s.MaxCLL = n
+			return true
+		}
+	case "MAXFALL":
+		if n, ok := parseLightLevel(value); ok {
+			s.MaxFALL = n
+			return true
+		}
+	}
+	return false
+}
+
+// typedTags returns the stream header X tags that represent the typed stream fields that are
+// set.
+func (s *Stream) typedTags() []string {
+	var t []string
+	if s.YSCSS != "" {
+		t = append(t, "YSCSS="+s.YSCSS)
+	}
+	if s.ColorRange != ColorRangeUnspecified {
+		t = append(t, "COLORRANGE="+s.ColorRange.String())
+	}
+	if s.MasteringDisplay != nil {
+		t = append(t, "MASTERING_DISPLAY="+s.MasteringDisplay.String())
+	}
+	if s.MaxCLL > 0 {
+		t = append(t, "MAXCLL="+strconv.Itoa(s.MaxCLL))
+	}
+	if s.MaxFALL > 0 {
+		t = append(t, "MAXFALL="+strconv.Itoa(s.MaxFALL))
+	}
+	return t
+}
+
+// CopyTags copies the X metadata and the typed fields stored as stream header tags, such as the
+// color range and HDR signaling, from stream in, so that they are carried through to the
+// output stream s. It should be called on an output stream before its header is written.
+func (s *Stream) CopyTags(in *Stream) {
+	s.Metadata = in.Metadata
+	s.YSCSS = in.YSCSS
+	s.ColorRange = in.ColorRange
+	s.MasteringDisplay = in.MasteringDisplay
+	s.MaxCLL = in.MaxCLL
+	s.MaxFALL = in.MaxFALL
+}
@@ -0,0 +1,89 @@
+package y4m
+
+import (
+	"image/color"
+	"strings"
+)
+
+// TextOptions configures DrawText.
+type TextOptions struct {
+	// Scale is the size of each pixel of the 5x7 font in luma samples. Zero is treated as 1.
+	Scale int
+	// Color is the color of the text.
+	Color color.YCbCr
+	// Box fills the text's bounding box with Background. Without it the text is drawn over the
+	// image.
+	Box        bool
+	Background color.YCbCr
+}
+
+// DrawText draws text onto frame f with a small built-in bitmap font covering printable ASCII,
+// working directly on the frame's planes, so that labels such as codec names and settings can
+// be stamped onto comparison streams. Lines are separated by '\n', and characters the font
+// lacks are drawn as filled boxes. The label, including a margin of one font pixel around the
+// text, has its top left corner at (x, y) and is clipped to the frame. Chroma samples shared by
+// text and image are mixed in proportion to the luma samples each covers. The alpha plane is
+// unchanged.
+func DrawText(f *Frame, text string, x, y int, o TextOptions) {
+	scale := maxInt(o.Scale, 1)
+	var lines [][]rune
+	cols := 0
+	for _, l := range strings.Split(text, "\n") {
+		lines = append(lines, []rune(l))
+		cols = maxInt(cols, len(lines[len(lines)-1]))
+	}
+	w := (cols*(glyphWidth+1) + 1) * scale
+	h := (len(lines)*(glyphHeight+1) + 1) * scale
+	x0, y0 := maxInt(x, 0), maxInt(y, 0)
+	x1, y1 := minInt(x+w, f.Width), minInt(y+h, f.Height)
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+	// ink reports whether the luma sample at (px, py), within the label, is part of a glyph
+	ink := func(px, py int) bool {
+		gx, gy := (px-x)/scale-1, (py-y)/scale-1
+		if gx < 0 || gy < 0 {
+			return false
+		}
+		line, row := gy/(glyphHeight+1), gy%(glyphHeight+1)
+		col, bit := gx/(glyphWidth+1), gx%(glyphWidth+1)
+		if line >= len(lines) || row >= glyphHeight || col >= len(lines[line]) || bit >= glyphWidth {
+			return false
+		}
+		return glyph(lines[line][col])[row]>>(glyphWidth-1-bit)&1 == 1
+	}
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			if ink(px, py) {
+				f.Y[py*f.Width+px] = o.Color.Y
+			} else if o.Box {
+				f.Y[py*f.Width+px] = o.Background.Y
+			}
+		}
+	}
+	if len(f.Cb) == 0 {
+		return
+	}
+	xss, yss := xSubsamplingFactor[f.Chroma], ySubsamplingFactor[f.Chroma]
+	cw, _ := chromaDims(f.Width, f.Height, f.Chroma)
+	n := xss * yss
+	for cy := y0 / yss; cy*yss < y1; cy++ {
+		for cx := x0 / xss; cx*xss < x1; cx++ {
+			// Count the luma samples of the chroma block covered by text and by the box
+			text, box := 0, 0
+			for py := maxInt(cy*yss, y0); py < minInt((cy+1)*yss, y1); py++ {
+				for px := maxInt(cx*xss, x0); px < minInt((cx+1)*xss, x1); px++ {
+					if ink(px, py) {
+						text++
+					} else if o.Box {
+						box++
+					}
+				}
+			}
+			k := cy*cw + cx
+			image := n - text - box
+			f.Cb[k] = byte((text*int(o.Color.Cb) + box*int(o.Background.Cb) + image*int(f.Cb[k]) + n/2) / n)
+			f.Cr[k] = byte((text*int(o.Color.Cr) + box*int(o.Background.Cr) + image*int(f.Cr[k]) + n/2) / n)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package y4m
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// TimeOfFrame returns the time at which frame n, counting from zero, is first displayed,
+// measured from the beginning of the stream using its frame rate.
+func (s *Stream) TimeOfFrame(n int) (time.Duration, error) {
+	err := s.checkFrameRate()
+	if err != nil {
+		return 0, err
+	}
+	return s.framesDuration(n)
+}
+
+// Duration returns the time taken to display frameCount frames at the stream's frame rate,
+// such as the running time of a stream of frameCount frames. The error wraps
+// ErrUnknownFrameRate if the frame rate is unknown.
+func (s *Stream) Duration(frameCount int) (time.Duration, error) {
+	err := s.checkFrameRate()
+	if err != nil {
+		return 0, err
+	}
+	if frameCount < 0 {
+		return 0, fmt.Errorf("frame count %d is negative", frameCount)
+	}
+	return s.framesDuration(frameCount)
+}
+
+// FrameDuration returns the time for which each frame is displayed, one period of the
+// stream's frame rate. The error wraps ErrUnknownFrameRate if the frame rate is unknown.
+func (s *Stream) FrameDuration() (time.Duration, error) {
+	return s.Duration(1)
+}
+
+// framesDuration returns the time taken to display n frames, truncated to a whole number of
+// nanoseconds. The product of n and the frame period is formed exactly, since it overflows
+// an int64 for long streams at rates such as 30000:1001.
+func (s *Stream) framesDuration(n int) (time.Duration, error) {
+	r := s.FrameRate
+	t := new(big.Int).Mul(big.NewInt(int64(n)), big.NewInt(int64(r.D)*int64(time.Second)))
+	t.Quo(t, big.NewInt(int64(r.N)))
+	if !t.IsInt64() {
+		return 0, fmt.Errorf("duration of %d frames at %v frames per second is out of range", n, r)
+	}
+	return time.Duration(t.Int64()), nil
+}
+
+// FrameAtTime returns the number of the frame, counting from zero, that is displayed at time
+// d, measured from the beginning of the stream using its frame rate.
+func (s *Stream) FrameAtTime(d time.Duration) (int, error) {
+	err := s.checkFrameRate()
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("time %v is negative", d)
+	}
+	r := s.FrameRate
+	return int(int64(d) * int64(r.N) / (int64(time.Second) * int64(r.D))), nil
+}
+
+// checkFrameRate checks that the stream has a known frame rate, as required to convert
+// between frame numbers and times.
+func (s *Stream) checkFrameRate() error {
+	if s.FrameRate == nil || s.FrameRate.N <= 0 || s.FrameRate.D <= 0 {
+		return fmt.Errorf("%w: %v", ErrUnknownFrameRate, s.FrameRate)
+	}
+	return nil
+}
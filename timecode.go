@@ -0,0 +1,67 @@
+package y4m
+
+import "fmt"
+
+// Timecode is an SMPTE timecode, which labels a frame with hours, minutes, seconds and a frame
+// count within the second. Seconds are counted in frames at the frame rate rounded to an
+// integer, the nominal rate, so at NTSC rates such as 30000:1001 non-drop-frame timecode runs
+// ahead of the clock. Drop-frame timecode corrects this by skipping frame labels, so that it
+// keeps to the clock to within a frame every ten minutes.
+type Timecode struct {
+	Hours     int
+	Minutes   int
+	Seconds   int
+	Frames    int
+	DropFrame bool // drop-frame timecode, whose frame count is separated by ';'
+}
+
+// NewTimecode returns the timecode of frame n, counting from zero, at frame rate r. Drop-frame
+// timecode requires an NTSC rate that is a multiple of 30000:1001, such as 29.97 or 59.94
+// frames per second. Hours wrap around after 24, as they do in SMPTE timecode.
+func NewTimecode(n int, r *Ratio, dropFrame bool) (Timecode, error) {
+	if !r.known() || r.N <= 0 || r.D <= 0 {
+		return Timecode{}, fmt.Errorf("timecode requires a known frame rate")
+	}
+	if n < 0 {
+		return Timecode{}, fmt.Errorf("frame %d has no timecode", n)
+	}
+	fps := maxInt(1, roundDiv(r.N, r.D))
+	if dropFrame {
+		if fps%30 != 0 || r.Cmp(&Ratio{N: fps * 1000, D: 1001}) != 0 {
+			return Timecode{}, fmt.Errorf("drop-frame timecode requires an NTSC frame rate, not %v", r)
+		}
+		// Frame labels 0 and 1 (0 to 3 at 59.94) are skipped at the start of every minute
+		// except each tenth, so count the labels skipped before frame n.
+		drop := fps / 15
+		perMinute := 60*fps - drop
+		perTenMinutes := 10*perMinute + drop
+		tens, k := n/perTenMinutes, n%perTenMinutes
+		n += 9 * drop * tens
+		if k > drop {
+			n += drop * ((k - drop) / perMinute)
+		}
+	}
+	s := n / fps
+	return Timecode{
+		Hours:     s / 3600 % 24,
+		Minutes:   s / 60 % 60,
+		Seconds:   s % 60,
+		Frames:    n % fps,
+		DropFrame: dropFrame,
+	}, nil
+}
+
+// String returns the timecode in the form HH:MM:SS:FF, or HH:MM:SS;FF for drop-frame timecode.
+func (t Timecode) String() string {
+	sep := ':'
+	if t.DropFrame {
+		sep = ';'
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%c%02d", t.Hours, t.Minutes, t.Seconds, sep, t.Frames)
+}
+
+// SetTimecode records timecode t in the frame's header as the X metadata field
+// TIMECODE=HH:MM:SS:FF, so that it travels with the frame through later processing.
+func (f *Frame) SetTimecode(t Timecode) error {
+	return f.SetMetadata("TIMECODE", t.String())
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "alpha", Summary: "add or remove an alpha plane", Run: runAlpha})
+}
+
+type alphaOptions struct {
+	inFile    string
+	outFile   string
+	matteFile string
+	value     int
+	strip     bool
+}
+
+func runAlpha(fs *flag.FlagSet, args []string) error {
+	o := new(alphaOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.StringVar(&o.matteFile, "matte", "", "stream whose luma gives the alpha of each frame")
+	fs.IntVar(&o.value, "a", 255, "constant alpha [0-255], used without -matte")
+	fs.BoolVar(&o.strip, "strip", false, "remove the alpha plane instead of adding one")
+	err := parse(fs, args, &o.inFile, &o.outFile)
+	if err != nil {
+		return err
+	}
+	return o.alpha()
+}
+
+func (o *alphaOptions) alpha() error {
+	if o.value < 0 || o.value > 255 {
+		return fmt.Errorf("alpha %d is outside [0, 255]", o.value)
+	}
+	if o.strip && o.matteFile != "" {
+		return fmt.Errorf("-strip cannot be combined with -matte")
+	}
+	in, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	var matte *y4m.Stream
+	if o.matteFile != "" {
+		matte, err = y4m.Open(o.matteFile)
+		if err != nil {
+			return err
+		}
+		defer matte.Close()
+		if matte.Width != in.Width || matte.Height != in.Height {
+			return fmt.Errorf("matte size %dx%d does not match input size %dx%d",
+				matte.Width, matte.Height, in.Width, in.Height)
+		}
+	}
+	out, err := createLike(o.outFile, in)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	chroma := "444alpha"
+	if o.strip {
+		chroma = "444"
+	}
+	err = out.ConvertChroma(chroma)
+	if err != nil {
+		return err
+	}
+	err = out.WriteHeader()
+	if err != nil {
+		return err
+	}
+	for {
+		frame, err := in.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if frame.Chroma != "444" && frame.Chroma != "444alpha" {
+			frame, err = frame.ConvertChroma("444")
+			if err != nil {
+				return err
+			}
+		}
+		switch {
+		case o.strip:
+			frame.RemoveAlpha()
+		case matte != nil:
+			m, err := matte.ParseFrame()
+			if err == io.EOF {
+				return fmt.Errorf("matte ends before the input")
+			} else if err != nil {
+				return err
+			}
+			err = frame.AttachAlpha(m.Y)
+			if err != nil {
+				return err
+			}
+		default:
+			err = frame.AddAlpha(byte(o.value))
+			if err != nil {
+				return err
+			}
+		}
+		err = out.WriteFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return out.Sync()
+}
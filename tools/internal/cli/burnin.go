@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "burnin", Summary: "render frame numbers and timecodes into frames", Run: runBurnIn})
+}
+
+type burnInOptions struct {
+	inFile  string
+	outFile string
+	start   int
+	tcMeta  bool
+	opts    y4m.BurnInOptions
+}
+
+func runBurnIn(fs *flag.FlagSet, args []string) error {
+	o := new(burnInOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.BoolVar(&o.opts.FrameNumber, "number", true, "render the frame number")
+	fs.BoolVar(&o.opts.Timecode, "tc", true, "render the timecode")
+	fs.BoolVar(&o.opts.DropFrame, "df", false, "use drop-frame timecode, for NTSC frame rates")
+	fs.BoolVar(&o.tcMeta, "tcmeta", false, "record the timecode of each frame as X metadata TIMECODE")
+	fs.IntVar(&o.start, "start", 0, "number of the first frame")
+	fs.IntVar(&o.opts.X, "x", 16, "horizontal position of the label")
+	fs.IntVar(&o.opts.Y, "y", 16, "vertical position of the label")
+	fs.IntVar(&o.opts.Scale, "scale", 0, "size of a font pixel in samples; 0 to suit the frame height")
+	err := parse(fs, args, &o.inFile, &o.outFile)
+	if err != nil {
+		return err
+	}
+	return o.burnIn()
+}
+
+func (o *burnInOptions) burnIn() error {
+	if o.start < 0 {
+		return fmt.Errorf("first frame number must not be negative")
+	}
+	in, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := createLike(o.outFile, in)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	err = out.WriteHeader()
+	if err != nil {
+		return err
+	}
+	for n := o.start; ; n++ {
+		frame, err := in.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		err = frame.BurnIn(n, in.FrameRate, o.opts)
+		if err != nil {
+			return err
+		}
+		if o.tcMeta {
+			tc, err := y4m.NewTimecode(n, in.FrameRate, o.opts.DropFrame)
+			if err != nil {
+				return err
+			}
+			err = frame.SetTimecode(tc)
+			if err != nil {
+				return err
+			}
+		}
+		err = out.WriteFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return out.Sync()
+}
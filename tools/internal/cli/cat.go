@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "cat", Summary: "concatenate streams", Run: runCat})
+}
+
+func runCat(fs *flag.FlagSet, args []string) error {
+	outFile := fs.String("o", "", "output file")
+	var opts y4m.ConcatOptions
+	fs.BoolVar(&opts.ConvertChroma, "convert", false, "convert inputs to the chroma format of the first")
+	fs.BoolVar(&opts.Fit, "fit", false, "crop or pad inputs to the size of the first, keeping them centred")
+	fs.BoolVar(&opts.IgnoreRate, "anyrate", false, "accept inputs whose frame rate differs from the first")
+	fs.IntVar(&opts.Crossfade, "fade", 0, "number of frames over which to crossfade between inputs")
+	ease := fs.Bool("ease", false, "ease the crossfade in and out instead of fading linearly")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s -o output [options] input...\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	err := parse(fs, args, outFile)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if opts.Crossfade < 0 {
+		return fmt.Errorf("crossfade length %d is negative", opts.Crossfade)
+	}
+	if *ease {
+		opts.Easing = y4m.EaseInOut
+	}
+	var ins []*y4m.Stream
+	for _, name := range fs.Args() {
+		s, err := y4m.Open(name)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		ins = append(ins, s)
+	}
+	out, err := y4m.NewStream(*outFile, 0, 0)
+	if err != nil {
+		return err
+	}
+	err = y4m.ConcatStreamsWith(out, opts, ins...)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
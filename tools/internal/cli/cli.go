@@ -0,0 +1,172 @@
+// Package cli implements the subcommands of the y4 tool, which the standalone tools, such as
+// y4info and y4clip, wrap. Default options for each subcommand can be given in a configuration
+// file.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/egtork/y4mlib"
+)
+
+// Command is a subcommand of the y4 tool.
+type Command struct {
+	Name    string
+	Summary string
+	Run     func(fs *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]*Command{}
+
+// errUsage is returned by a command when its arguments are incomplete; usage has been printed.
+var errUsage = errors.New("invalid usage")
+
+func register(c *Command) {
+	commands[c.Name] = c
+}
+
+// Commands returns the registered subcommands sorted by name.
+func Commands() []*Command {
+	var cs []*Command
+	for _, c := range commands {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Name < cs[j].Name })
+	return cs
+}
+
+// Run runs the named subcommand with args, reporting its program name as prog in usage
+// messages, and returns the process exit status. The command's options in the configuration
+// file are parsed first, so that args override them.
+func Run(prog, name string, args []string) int {
+	c, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: unknown command %q\n", prog, name)
+		return 2
+	}
+	cfg, err := configArgs(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prog, err)
+		return 2
+	}
+	fs := flag.NewFlagSet(prog, flag.ContinueOnError)
+	err = c.Run(fs, append(cfg, args...))
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err == errUsage {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Main runs the named subcommand with the process arguments and exits.
+func Main(prog, name string) {
+	os.Exit(Run(prog, name, os.Args[1:]))
+}
+
+// parse parses args into fs, printing usage and returning errUsage if any of the required
+// string flags are empty.
+func parse(fs *flag.FlagSet, args []string, required ...*string) error {
+	err := fs.Parse(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	for _, r := range required {
+		if *r == "" {
+			fs.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+// parseTimestamp parses a time of the form [[HH:]MM:]SS[.fff], such as "01:23.5".
+func parseTimestamp(ts string) (time.Duration, error) {
+	parts := strings.Split(ts, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	sec, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || sec < 0 {
+		return 0, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	d := time.Duration(math.Round(sec * float64(time.Second)))
+	unit := time.Minute
+	for k := len(parts) - 2; k >= 0; k-- {
+		n, err := strconv.Atoi(parts[k])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", ts)
+		}
+		d += time.Duration(n) * unit
+		unit *= 60
+	}
+	return d, nil
+}
+
+// framesBefore returns the number of frames of stream s that begin before time d.
+func framesBefore(s *y4m.Stream, d time.Duration) (int, error) {
+	n, err := s.FrameAtTime(d)
+	if err != nil {
+		return 0, err
+	}
+	// The frame displayed at time d is included only if it starts earlier
+	t, err := s.TimeOfFrame(n)
+	if err != nil {
+		return 0, err
+	}
+	if t < d {
+		n++
+	}
+	return n, nil
+}
+
+// parseRate parses a frame rate flag with y4m.ParseRatio, rejecting an unknown rate of 0:0.
+func parseRate(s string) (*y4m.Ratio, error) {
+	r, err := y4m.ParseRatio(s)
+	if err != nil || r.N <= 0 {
+		return nil, fmt.Errorf("could not parse frame rate %q", s)
+	}
+	return r, nil
+}
+
+// createLike creates a named output stream with the same header fields as stream s.
+func createLike(name string, s *y4m.Stream) (*y4m.Stream, error) {
+	out, err := y4m.NewStream(name, s.Width, s.Height)
+	if err != nil {
+		return nil, err
+	}
+	out.SetChroma(s.Chroma)
+	out.FrameRate = s.FrameRate
+	out.Interlacing = s.Interlacing
+	out.SampleAspectRatio = s.SampleAspectRatio
+	out.CopyTags(s)
+	return out, nil
+}
+
+// stringList is a flag.Value collecting the values of a repeated string flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,636 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "clip", Summary: "crop and truncate a stream", Run: runClip})
+}
+
+type clipOptions struct {
+	inFile       string
+	outFile      string
+	newWidth     int
+	newHeight    int
+	xOffset      int
+	yOffset      int
+	startFrame   int
+	endFrame     int
+	stripHeaders bool
+	sar          string
+	dar          string
+	interlacing  string
+	dropMeta     bool
+	expand       bool
+	recover      bool
+	align        bool
+	reverse      bool
+	step         int
+	startTime    string
+	endTime      string
+	autoCrop     bool
+	outputs      stringList
+}
+
+func runClip(fs *flag.FlagSet, args []string) error {
+	o := new(clipOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file; - for standard input")
+	fs.StringVar(&o.outFile, "o", "", "output file; - for standard output")
+	fs.IntVar(&o.newWidth, "w", -1, "cropped width; -1 for original width")
+	fs.IntVar(&o.newHeight, "h", -1, "cropped height; -1 for original height")
+	fs.IntVar(&o.xOffset, "x", -1, "horizontal offset; -1 to center")
+	fs.IntVar(&o.yOffset, "y", -1, "vertical offset; -1 to center")
+	fs.IntVar(&o.startFrame, "s", 1, "start frame")
+	fs.IntVar(&o.endFrame, "e", -1, "end frame; -1 for last frame of input stream")
+	fs.StringVar(&o.startTime, "ss", "", "start time [[HH:]MM:]SS[.fff]; overrides -s")
+	fs.StringVar(&o.endTime, "to", "", "end time [[HH:]MM:]SS[.fff], exclusive; overrides -e")
+	fs.BoolVar(&o.stripHeaders, "strip", false, "strip header information")
+	fs.StringVar(&o.sar, "sar", "", "output sample aspect ratio N:D; empty to keep input value")
+	fs.StringVar(&o.dar, "dar", "", "output display aspect ratio N:D, such as 16:9; overrides -sar")
+	fs.StringVar(&o.interlacing, "interlace", "", "output interlacing {p, t, b, m, ?}; empty to derive from input")
+	fs.BoolVar(&o.dropMeta, "dropmeta", false, "drop X metadata from stream and frame headers")
+	fs.BoolVar(&o.expand, "expand", false, "expand repeat-field and repeated frames to one frame per period")
+	fs.BoolVar(&o.recover, "recover", false, "skip corrupt frames instead of stopping")
+	fs.BoolVar(&o.align, "align", false, "round offsets down to multiples of the chroma subsampling")
+	fs.BoolVar(&o.reverse, "reverse", false, "write frames in reverse order")
+	fs.IntVar(&o.step, "step", 1, "keep every nth frame, starting with the start frame")
+	fs.BoolVar(&o.autoCrop, "autocrop", false, "crop away constant black borders; overrides -w, -h, -x and -y")
+	fs.Var(&o.outputs, "out", "additional output \"FILE [-s] [-e] [-ss] [-to] [-w] [-h] [-x] [-y]\"; may be repeated")
+	err := parse(fs, args, &o.inFile, &o.outFile)
+	if err != nil {
+		return err
+	}
+	if len(o.outputs) > 0 {
+		return o.clipMany()
+	}
+	return o.clip()
+}
+
+func (o *clipOptions) clip() error {
+	sIn, err := o.openInput()
+	if err != nil {
+		return err
+	}
+	defer sIn.Close()
+	sIn.Recover = o.recover
+	err = o.setAndCheckUserInputs(sIn)
+	if err != nil {
+		return err
+	}
+	sOut, err := o.openOutput(sIn)
+	if err != nil {
+		return err
+	}
+	defer sOut.Close()
+	if o.copiesFrames(sIn, sOut) {
+		err = sIn.Trim(sOut, o.startFrame-1, o.endFrame)
+		if err != nil {
+			return err
+		}
+		return sOut.Sync()
+	}
+	next := sIn.ParseFrame
+	if o.reverse {
+		next, err = o.reverseFrames(sIn)
+		if err != nil {
+			return err
+		}
+	} else {
+		// skip frames
+		for k := 1; k < o.startFrame; k++ {
+			err := sIn.SkipFrame()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	// copy frames
+	expander := new(y4m.RepeatExpander)
+	for k := o.startFrame; o.endFrame == -1 || k <= o.endFrame; k += o.step {
+		if k > o.startFrame && !o.reverse {
+			err := skipFrames(sIn, o.step-1)
+			if err == io.EOF && o.endFrame == -1 {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		frame, err := next()
+		if err == io.EOF && o.endFrame == -1 {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		err = o.writeFrame(sIn, sOut, frame, expander)
+		if err != nil {
+			return err
+		}
+	}
+	reportRecovery(sIn)
+	return sOut.Sync()
+}
+
+// clipOutput is one of the outputs written in a single pass over the input, with its own
+// frame range and crop.
+type clipOutput struct {
+	o        *clipOptions
+	s        *y4m.Stream
+	expander *y4m.RepeatExpander
+}
+
+// clipMany writes the main output and each additional output given with -out in a single
+// pass over the input, so that several excerpts of a large stream are cut with one read.
+// Frames selected by no output are skipped without being parsed.
+func (o *clipOptions) clipMany() error {
+	if o.reverse {
+		return fmt.Errorf("-reverse cannot be combined with -out")
+	}
+	jobs := []*clipOptions{o}
+	for _, spec := range o.outputs {
+		j, err := o.parseOutput(spec)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, j)
+	}
+	sIn, err := o.openInput()
+	if err != nil {
+		return err
+	}
+	defer sIn.Close()
+	sIn.Recover = o.recover
+	var outs []*clipOutput
+	last, stdout := 0, 0
+	for _, j := range jobs {
+		if j.outFile == "-" {
+			stdout++
+		}
+		if stdout > 1 {
+			return fmt.Errorf("only one output can be standard output")
+		}
+		err = j.setAndCheckUserInputs(sIn)
+		if err != nil {
+			return fmt.Errorf("%s: %w", j.outFile, err)
+		}
+		sOut, err := j.openOutput(sIn)
+		if err != nil {
+			return err
+		}
+		defer sOut.Close()
+		outs = append(outs, &clipOutput{o: j, s: sOut, expander: new(y4m.RepeatExpander)})
+		if last != -1 && (j.endFrame == -1 || j.endFrame > last) {
+			last = j.endFrame
+		}
+	}
+	for k := 1; last == -1 || k <= last; k++ {
+		var selected []*clipOutput
+		for _, out := range outs {
+			if out.o.selects(k) {
+				selected = append(selected, out)
+			}
+		}
+		var frame *y4m.Frame
+		if len(selected) == 0 {
+			err = sIn.SkipFrame()
+		} else {
+			frame, err = sIn.ParseFrame()
+		}
+		if err == io.EOF {
+			return checkEndFrames(sIn, outs, k-1)
+		}
+		if err != nil {
+			return err
+		}
+		for n, out := range selected {
+			// Outputs crop and rewrite the frame, so all but the last are given a copy
+			f := frame
+			if n < len(selected)-1 {
+				f = frame.Copy()
+			}
+			err = out.o.writeFrame(sIn, out.s, f, out.expander)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return syncOutputs(sIn, outs)
+}
+
+// checkEndFrames checks, once the input has ended after n frames, that no output was given a
+// start or end frame beyond it, and then completes the outputs.
+func checkEndFrames(sIn *y4m.Stream, outs []*clipOutput, n int) error {
+	for _, out := range outs {
+		if out.o.startFrame > n {
+			return fmt.Errorf("%s: start frame (%d) exceeds number of frames in input stream (%d)",
+				out.o.outFile, out.o.startFrame, n)
+		}
+		if out.o.endFrame > n {
+			return fmt.Errorf("%s: end frame (%d) exceeds number of frames in input stream (%d)",
+				out.o.outFile, out.o.endFrame, n)
+		}
+	}
+	return syncOutputs(sIn, outs)
+}
+
+// syncOutputs reports any data of the input dropped in recovery mode and flushes the outputs.
+func syncOutputs(sIn *y4m.Stream, outs []*clipOutput) error {
+	reportRecovery(sIn)
+	for _, out := range outs {
+		err := out.s.Sync()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseOutput parses the specification of an additional output given with -out: a file name
+// followed by the -s, -e, -ss, -to, -w, -h, -x and -y flags, which default to the whole input
+// stream as for the main output. The other options are shared with the main output.
+func (o *clipOptions) parseOutput(spec string) (*clipOptions, error) {
+	args := strings.Fields(spec)
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && args[0] != "-") {
+		return nil, fmt.Errorf("output %q must begin with a file name", spec)
+	}
+	j := *o
+	j.outputs = nil
+	j.outFile = args[0]
+	fs := flag.NewFlagSet(j.outFile, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.IntVar(&j.newWidth, "w", -1, "")
+	fs.IntVar(&j.newHeight, "h", -1, "")
+	fs.IntVar(&j.xOffset, "x", -1, "")
+	fs.IntVar(&j.yOffset, "y", -1, "")
+	fs.IntVar(&j.startFrame, "s", 1, "")
+	fs.IntVar(&j.endFrame, "e", -1, "")
+	fs.StringVar(&j.startTime, "ss", "", "")
+	fs.StringVar(&j.endTime, "to", "", "")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return nil, fmt.Errorf("output %q: %v", spec, err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("output %q: unexpected argument %q", spec, fs.Arg(0))
+	}
+	return &j, nil
+}
+
+// selects reports whether frame k of the input, counting from one, is written to the output.
+func (o *clipOptions) selects(k int) bool {
+	return k >= o.startFrame && (o.endFrame == -1 || k <= o.endFrame) && (k-o.startFrame)%o.step == 0
+}
+
+// openOutput creates the output stream with header fields derived from input stream sIn and
+// writes its header, unless headers are stripped.
+func (o *clipOptions) openOutput(sIn *y4m.Stream) (*y4m.Stream, error) {
+	sOut, err := o.createOutput()
+	if err != nil {
+		return nil, err
+	}
+	sOut.Chroma = sIn.Chroma
+	sOut.FrameRate = decimatedRate(sIn.FrameRate, o.step)
+	sOut.XSubsamplingFactor = sIn.XSubsamplingFactor
+	sOut.YSubsamplingFactor = sIn.YSubsamplingFactor
+	err = o.setOutputHeaderFields(sIn, sOut)
+	if err == nil && !o.stripHeaders {
+		err = sOut.WriteHeader()
+	}
+	if err != nil {
+		sOut.Close()
+		return nil, err
+	}
+	return sOut, nil
+}
+
+// writeFrame crops a frame of input stream sIn, expands it with expander if repeated frames
+// are expanded, and writes the result to output stream sOut.
+func (o *clipOptions) writeFrame(sIn, sOut *y4m.Stream, frame *y4m.Frame, expander *y4m.RepeatExpander) error {
+	if !o.stripHeaders {
+		stampI(frame.Header, sIn, sOut)
+	}
+	if sOut.Height != sIn.Height || sOut.Width != sIn.Width {
+		err := frame.Crop(o.newWidth, o.newHeight, o.xOffset, o.yOffset)
+		if err != nil {
+			return err
+		}
+	}
+	frames := []*y4m.Frame{frame}
+	if o.expand {
+		var err error
+		frames, err = expander.Push(frame)
+		if err != nil {
+			return err
+		}
+	}
+	for _, frame := range frames {
+		if !o.stripHeaders {
+			o.rewriteFrameHeader(frame.Header)
+			err := sOut.WriteFrameHeader(frame)
+			if err != nil {
+				return err
+			}
+		}
+		err := sOut.WriteFrameData(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportRecovery reports on standard error any data of stream s dropped in recovery mode.
+func reportRecovery(s *y4m.Stream) {
+	if s.Recovered.Bytes > 0 {
+		fmt.Fprintf(os.Stderr, "recovered from corrupt input: dropped %d frames, skipped %d bytes\n",
+			s.Recovered.Frames, s.Recovered.Bytes)
+	}
+}
+
+// openInput opens the input stream, reading standard input if the input file is "-".
+func (o *clipOptions) openInput() (*y4m.Stream, error) {
+	if o.inFile == "-" {
+		return y4m.OpenReader(os.Stdin)
+	}
+	return y4m.Open(o.inFile)
+}
+
+// createOutput creates the output stream, writing to standard output if the output file is
+// "-".
+func (o *clipOptions) createOutput() (*y4m.Stream, error) {
+	if o.outFile == "-" {
+		return y4m.NewStreamWriter(os.Stdout, o.newWidth, o.newHeight), nil
+	}
+	return y4m.NewStream(o.outFile, o.newWidth, o.newHeight)
+}
+
+func (o *clipOptions) setAndCheckUserInputs(s *y4m.Stream) error {
+	if o.reverse && (o.expand || o.recover) {
+		return fmt.Errorf("-reverse cannot be combined with -expand or -recover")
+	}
+	if o.inFile == "-" && (o.reverse || o.autoCrop) {
+		return fmt.Errorf("-reverse and -autocrop cannot be used when reading standard input")
+	}
+	err := o.setFramesFromTimes(s)
+	if err != nil {
+		return err
+	}
+	if o.autoCrop {
+		err = o.setAutoCrop(s)
+		if err != nil {
+			return err
+		}
+	}
+	if o.startFrame < 1 {
+		return fmt.Errorf("start frame must be greater than 0")
+	}
+	if o.step < 1 {
+		return fmt.Errorf("step must be greater than 0")
+	}
+	if o.endFrame == -1 {
+		// do nothing
+	} else if o.endFrame < 1 {
+		return fmt.Errorf("end frame must be -1 or greater than 0")
+	}
+	// Mono streams have no chroma planes, so any alignment will do. The chroma lines of
+	// interlaced 4:2:0 streams belong to alternate fields, which doubles the vertical alignment.
+	xss, yss := s.CropGrid()
+	reason := "chroma subsampling"
+	if yss != s.YSubsamplingFactor && s.Chroma != "mono" {
+		reason = "interlaced chroma subsampling"
+	}
+	if o.newWidth == -1 {
+		o.newWidth = s.Width
+	} else if o.newWidth < 1 {
+		return fmt.Errorf("cropped width must be -1 or greater than 0")
+	} else if o.newWidth > s.Width {
+		return fmt.Errorf("cropped width cannot exceed original width (%d)", s.Width)
+	} else if o.newWidth%xss != 0 {
+		return fmt.Errorf("choose width as a multiple of %d to accomodate %s", xss, reason)
+	}
+	if o.newHeight == -1 {
+		o.newHeight = s.Height
+	} else if o.newHeight < 1 {
+		return fmt.Errorf("cropped height must be -1 or greater than 0")
+	} else if o.newHeight > s.Height {
+		return fmt.Errorf("cropped height cannot exceed original height (%d)", s.Height)
+	} else if o.newHeight%yss != 0 {
+		return fmt.Errorf("choose height as a multiple of %d to accomodate %s", yss, reason)
+	}
+	if o.xOffset == -1 {
+		o.xOffset = xss * ((s.Width - o.newWidth) / 2 / xss)
+	}
+	if o.align {
+		o.xOffset -= o.xOffset % xss
+	} else if o.xOffset%xss != 0 {
+		return fmt.Errorf("choose horizontal offset as a multiple of %d to accomodate %s", xss, reason)
+	}
+	if o.xOffset+o.newWidth > s.Width {
+		return fmt.Errorf("horizontal offset + cropped width cannot exceed original width (%d)", s.Width)
+	}
+	if o.yOffset == -1 {
+		o.yOffset = yss * ((s.Height - o.newHeight) / 2 / yss)
+	}
+	if o.align {
+		o.yOffset -= o.yOffset % yss
+	} else if o.yOffset%yss != 0 {
+		return fmt.Errorf("choose vertical offset as a multiple of %d to accomodate %s", yss, reason)
+	}
+	if o.yOffset+o.newHeight > s.Height {
+		return fmt.Errorf("vertical offset + cropped height cannot exceed original height (%d)", s.Height)
+	}
+	return nil
+}
+
+// Letterbox detection examines autoCropFrames frames spread over the stream, and treats rows
+// and columns with mean luma up to autoCropThreshold as black.
+const (
+	autoCropFrames    = 20
+	autoCropThreshold = 24
+)
+
+// setAutoCrop sets the crop to the active picture area of stream s inside any black borders.
+func (o *clipOptions) setAutoCrop(s *y4m.Stream) error {
+	r, err := s.DetectActiveArea(autoCropFrames, autoCropThreshold)
+	if err != nil {
+		return err
+	}
+	o.newWidth, o.newHeight = r.Dx(), r.Dy()
+	o.xOffset, o.yOffset = r.Min.X, r.Min.Y
+	fmt.Fprintf(os.Stderr, "autocrop: %dx%d at offset %d,%d\n", o.newWidth, o.newHeight, o.xOffset, o.yOffset)
+	return nil
+}
+
+// setFramesFromTimes converts the start and end times, if given, into start and end frames.
+func (o *clipOptions) setFramesFromTimes(s *y4m.Stream) error {
+	if o.startTime != "" {
+		d, err := parseTimestamp(o.startTime)
+		if err != nil {
+			return err
+		}
+		n, err := s.FrameAtTime(d)
+		if err != nil {
+			return err
+		}
+		o.startFrame = n + 1
+	}
+	if o.endTime != "" {
+		d, err := parseTimestamp(o.endTime)
+		if err != nil {
+			return err
+		}
+		o.endFrame, err = framesBefore(s, d)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setOutputHeaderFields populates the sample aspect ratio, interlacing and metadata fields of
+// the output stream so that they remain consistent with the transformations being applied.
+func (o *clipOptions) setOutputHeaderFields(sIn, sOut *y4m.Stream) error {
+	sOut.SampleAspectRatio = sIn.SampleAspectRatio
+	if o.sar != "" {
+		r, err := y4m.ParseRatio(o.sar)
+		if err != nil {
+			return fmt.Errorf("could not parse sample aspect ratio %q", o.sar)
+		}
+		sOut.SampleAspectRatio = r
+	}
+	if o.dar != "" {
+		r, err := y4m.ParseRatio(o.dar)
+		if err != nil {
+			return fmt.Errorf("could not parse display aspect ratio %q", o.dar)
+		}
+		err = sOut.SetDisplayAspectRatio(r)
+		if err != nil {
+			return err
+		}
+	}
+	sOut.Interlacing = sIn.Interlacing
+	if o.fieldOrderSwapped() {
+		sOut.Interlacing = swapFieldOrder(sOut.Interlacing)
+	}
+	if o.interlacing != "" {
+		switch o.interlacing {
+		case "p", "t", "b", "m", "?":
+			sOut.Interlacing = o.interlacing
+		default:
+			return fmt.Errorf("unrecognized interlacing mode %q", o.interlacing)
+		}
+	}
+	if !o.dropMeta {
+		sOut.CopyTags(sIn)
+	}
+	return nil
+}
+
+// copiesFrames reports whether the selected frames are copied unchanged, in which case they
+// can be copied as a byte range without being parsed.
+func (o *clipOptions) copiesFrames(sIn, sOut *y4m.Stream) bool {
+	return sOut.Width == sIn.Width && sOut.Height == sIn.Height && !o.stripHeaders &&
+		!o.dropMeta && !o.expand && !o.recover && !o.reverse && o.step == 1 && !o.fieldOrderSwapped() &&
+		(sOut.Interlacing != "m" || sIn.Interlacing == "m")
+}
+
+// skipFrames skips n frames of stream s.
+func skipFrames(s *y4m.Stream, n int) error {
+	for k := 0; k < n; k++ {
+		err := s.SkipFrame()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decimatedRate returns frame rate r divided by step, or r if it is unknown.
+func decimatedRate(r *y4m.Ratio, step int) *y4m.Ratio {
+	if r == nil || r.N == 0 || step == 1 {
+		return r
+	}
+	return r.Div(&y4m.Ratio{N: step, D: 1})
+}
+
+// fieldOrderSwapped reports whether the top field of the input becomes the bottom field of the
+// output, either because the crop shifts the image by an odd number of lines or because the
+// frames are reversed, which reverses the display order of their fields. Both together cancel.
+func (o *clipOptions) fieldOrderSwapped() bool {
+	return (o.yOffset%2 == 1) != o.reverse
+}
+
+// reverseFrames returns a function that parses the selected frames of stream s from last to
+// first, using a frame index to seek to each one, and then returns io.EOF. With a step, the
+// same frames are selected as when reading forwards.
+func (o *clipOptions) reverseFrames(s *y4m.Stream) (func() (*y4m.Frame, error), error) {
+	idx, err := s.BuildIndex()
+	if err != nil {
+		return nil, err
+	}
+	end := o.endFrame
+	if end == -1 {
+		end = len(idx)
+	}
+	if end > len(idx) {
+		return nil, fmt.Errorf("end frame (%d) exceeds number of frames in input stream (%d)", end, len(idx))
+	}
+	n := end - (end-o.startFrame)%o.step
+	return func() (*y4m.Frame, error) {
+		if n < o.startFrame {
+			return nil, io.EOF
+		}
+		err := s.SeekFrame(idx, n-1)
+		if err != nil {
+			return nil, err
+		}
+		n -= o.step
+		return s.ParseFrame()
+	}, nil
+}
+
+// swapFieldOrder exchanges top and bottom field designations in interlacing mode or frame
+// presentation value m.
+func swapFieldOrder(m string) string {
+	swapped := map[string]string{"t": "b", "b": "t", "T": "B", "B": "T"}
+	if n, ok := swapped[m]; ok {
+		return n
+	}
+	return m
+}
+
+// stampI gives a frame header without an I field the one implied by the interlacing mode of
+// input stream sIn when output stream sOut is mixed-mode, since every frame of a mixed-mode
+// stream must have one.
+func stampI(h *y4m.FrameHeader, sIn, sOut *y4m.Stream) {
+	if i := sIn.IField(); sOut.Interlacing == "m" && h.I == nil && i != nil {
+		h.SetI(i.Presentation, i.Temporal, i.Spatial)
+	}
+}
+
+// rewriteFrameHeader regenerates the raw frame header bytes when the frames are reversed or
+// metadata is dropped, so that frame headers agree with the output stream header. Crop swaps
+// the field order of frames cropped by an odd number of lines itself.
+func (o *clipOptions) rewriteFrameHeader(h *y4m.FrameHeader) {
+	swap := o.reverse && h.I != nil
+	if !swap && !(o.dropMeta && len(h.Metadata) > 0) {
+		return
+	}
+	if swap {
+		i := *h.I
+		i.Presentation = swapFieldOrder(string(i.Presentation))[0]
+		h.I = &i
+	}
+	if o.dropMeta {
+		h.Metadata = nil
+	}
+	h.Raw = h.Bytes()
+}
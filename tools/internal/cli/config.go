@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configEnv names the environment variable that gives the path of the configuration file.
+// Without it, the file is y4/config in the user's configuration directory, such as
+// ~/.config/y4/config on Linux.
+const configEnv = "Y4_CONFIG"
+
+// configArgs returns the default options of the named command given in the configuration
+// file, as flags to be parsed before those on the command line, which override them. A
+// missing configuration file gives no options, unless it was named by Y4_CONFIG.
+func configArgs(name string) ([]string, error) {
+	path, named := os.Getenv(configEnv), true
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, nil
+		}
+		path, named = filepath.Join(dir, "y4", "config"), false
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) && !named {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseConfig(f, path, name)
+}
+
+// parseConfig parses configuration file r, read from path, and returns the options of the
+// named command as flags. The file holds a section for each command, headed by its name in
+// square brackets, with an option flag = value on each line, where the flag is given without
+// its leading '-'. Blank lines and lines beginning with '#' are ignored.
+func parseConfig(r io.Reader, path, name string) ([]string, error) {
+	var args []string
+	section := ""
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		t := strings.TrimSpace(sc.Text())
+		switch {
+		case t == "" || t[0] == '#':
+			// do nothing
+		case t[0] == '[' && t[len(t)-1] == ']':
+			section = strings.TrimSpace(t[1 : len(t)-1])
+		default:
+			key, value, ok := strings.Cut(t, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" || strings.HasPrefix(key, "-") {
+				return nil, fmt.Errorf("%s:%d: expected flag = value", path, line)
+			}
+			if section == "" {
+				return nil, fmt.Errorf("%s:%d: option %s is not in a [command] section", path, line, key)
+			}
+			if section == name {
+				args = append(args, "-"+key+"="+strings.TrimSpace(value))
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return args, nil
+}
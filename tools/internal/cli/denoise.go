@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"flag"
+	"io"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "denoise", Summary: "average frames over time to reduce noise", Run: runDenoise})
+}
+
+type denoiseOptions struct {
+	inFile    string
+	outFile   string
+	radius    int
+	threshold int
+}
+
+func runDenoise(fs *flag.FlagSet, args []string) error {
+	o := new(denoiseOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.IntVar(&o.radius, "r", 1, "number of frames either side of each frame to average")
+	fs.IntVar(&o.threshold, "t", 0, "largest sample difference treated as noise rather than motion; 0 for none")
+	err := parse(fs, args, &o.inFile, &o.outFile)
+	if err != nil {
+		return err
+	}
+	return o.denoise()
+}
+
+func (o *denoiseOptions) denoise() error {
+	d, err := y4m.NewTemporalDenoiser(o.radius, o.threshold)
+	if err != nil {
+		return err
+	}
+	in, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := createLike(o.outFile, in)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	err = out.WriteHeader()
+	if err != nil {
+		return err
+	}
+	for {
+		frame, err := in.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		frame, err = d.Push(frame)
+		if err != nil {
+			return err
+		}
+		if frame == nil {
+			continue
+		}
+		err = out.WriteFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+	frames, err := d.Flush()
+	if err != nil {
+		return err
+	}
+	for _, frame := range frames {
+		err = out.WriteFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return out.Sync()
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "detect", Summary: "find black and frozen frames", Run: runDetect})
+}
+
+type detectOptions struct {
+	inFile    string
+	black     float64
+	freeze    float64
+	minLength int
+}
+
+func runDetect(fs *flag.FlagSet, args []string) error {
+	o := new(detectOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.Float64Var(&o.black, "black", 20, "largest mean luma of a black frame; negative to skip")
+	fs.Float64Var(&o.freeze, "freeze", 0.5, "largest mean luma change of a frozen frame; negative to skip")
+	fs.IntVar(&o.minLength, "min", 1, "shortest run of frames to report")
+	err := parse(fs, args, &o.inFile)
+	if err != nil {
+		return err
+	}
+	return o.detect()
+}
+
+func (o *detectOptions) detect() error {
+	s, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	black := y4m.NewBlackDetector(o.black, o.minLength)
+	freeze := y4m.NewFreezeDetector(o.freeze, o.minLength)
+	for {
+		frame, err := s.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		black.Push(frame)
+		err = freeze.Push(frame)
+		if err != nil {
+			return err
+		}
+	}
+	if o.black >= 0 {
+		printRanges(s, "black", black.Ranges())
+	}
+	if o.freeze >= 0 {
+		printRanges(s, "frozen", freeze.Ranges())
+	}
+	return nil
+}
+
+// printRanges prints each range of frames with the given label, numbering frames from 1 and
+// adding the times of the range if the frame rate of stream s is known.
+func printRanges(s *y4m.Stream, label string, ranges []y4m.FrameRange) {
+	for _, r := range ranges {
+		fmt.Printf("%s: frames %d-%d (%d frames)", label, r.Start+1, r.End, r.Len())
+		start, errStart := s.TimeOfFrame(r.Start)
+		end, errEnd := s.TimeOfFrame(r.End)
+		if errStart == nil && errEnd == nil {
+			fmt.Printf(", %s to %s", formatTime(start), formatTime(end))
+		}
+		fmt.Println()
+	}
+}
+
+// formatTime formats d as HH:MM:SS.fff.
+func formatTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
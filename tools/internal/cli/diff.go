@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "diff", Summary: "compare two streams frame by frame", Run: runDiff})
+}
+
+type diffOptions struct {
+	all    bool
+	maxErr bool
+}
+
+var errDiffer = errors.New("streams differ")
+
+func runDiff(fs *flag.FlagSet, args []string) error {
+	o := new(diffOptions)
+	fs.BoolVar(&o.all, "all", false, "report every differing frame instead of stopping at the first")
+	fs.BoolVar(&o.maxErr, "maxerr", false, "print the maximum absolute error of every frame")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [options] a.y4m b.y4m\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+	a, err := y4m.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	b, err := y4m.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return o.diff(a, b)
+}
+
+func (o *diffOptions) diff(a, b *y4m.Stream) error {
+	if a.Width != b.Width || a.Height != b.Height || a.Chroma != b.Chroma {
+		fmt.Printf("geometry differs: %dx%d %s, %dx%d %s\n", a.Width, a.Height, a.Chroma,
+			b.Width, b.Height, b.Chroma)
+		return errDiffer
+	}
+	if !o.all && !o.maxErr {
+		d, err := y4m.CompareStreams(a, b)
+		if err != nil {
+			return err
+		}
+		if d == nil {
+			return nil
+		}
+		if d.Frame < 0 {
+			fmt.Println(d.Reason)
+		} else {
+			fmt.Printf("frame %d: %s\n", d.Frame+1, d.Reason)
+		}
+		return errDiffer
+	}
+	differ := false
+	if string(a.Header()) != string(b.Header()) {
+		fmt.Printf("stream headers differ:\n  %s  %s", a.Header(), b.Header())
+		differ = true
+	}
+	for n := 1; ; n++ {
+		fa, errA := a.ParseFrame()
+		fb, errB := b.ParseFrame()
+		if errA == io.EOF && errB == io.EOF {
+			break
+		} else if errA == io.EOF || errB == io.EOF {
+			fmt.Printf("frame counts differ: one stream ends before frame %d\n", n)
+			return errDiffer
+		} else if errA != nil {
+			return errA
+		} else if errB != nil {
+			return errB
+		}
+		d, err := fa.Diff(fb)
+		if err != nil {
+			return err
+		}
+		headers := string(fa.Header.Bytes()) != string(fb.Header.Bytes())
+		changed := headers
+		for _, p := range d {
+			changed = changed || p.Differing > 0
+		}
+		if o.maxErr {
+			fmt.Printf("frame %d: max error %s\n", n, formatPlanes(fa, d, func(p y4m.PlaneDiff) int { return p.MaxAbs }))
+		}
+		if !changed {
+			continue
+		}
+		differ = true
+		if headers {
+			fmt.Printf("frame %d: headers differ: %q, %q\n", n, fa.Header.Bytes(), fb.Header.Bytes())
+		}
+		fmt.Printf("frame %d: differing bytes %s\n", n, formatPlanes(fa, d, func(p y4m.PlaneDiff) int { return p.Differing }))
+		i, x, y, found, err := fa.FirstDifference(fb)
+		if err != nil {
+			return err
+		}
+		if found {
+			fmt.Printf("frame %d: first difference in %s at (%d, %d): %d, %d\n", n, y4m.PlaneName(i), x, y,
+				fa.Plane(i).At(x, y), fb.Plane(i).At(x, y))
+		}
+		if !o.all {
+			return errDiffer
+		}
+	}
+	if differ {
+		return errDiffer
+	}
+	return nil
+}
+
+// formatPlanes formats value v of each plane of frame f, omitting planes f does not have.
+func formatPlanes(f *y4m.Frame, d [4]y4m.PlaneDiff, v func(y4m.PlaneDiff) int) string {
+	var s []string
+	for i, p := range d {
+		if f.Plane(i).Data == nil {
+			continue
+		}
+		s = append(s, fmt.Sprintf("%s %d", y4m.PlaneName(i), v(p)))
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+)
+
+// codeLengthCodeOrder is the order in which the lengths of the code length code are written in
+// a lossless WebP image.
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// encodeWebP writes img as a lossless WebP image. The pixels are coded as literals after the
+// subtract green transform, with a single set of Huffman codes for the whole image; there is
+// no prediction or backward referencing, so files are larger than those of a full encoder.
+func encodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	if b.Dx() > 1<<14 || b.Dy() > 1<<14 {
+		return fmt.Errorf("image size %dx%d exceeds WebP limit of %d", b.Dx(), b.Dy(), 1<<14)
+	}
+	// Pixels after the subtract green transform, and the frequency of each symbol
+	pix := make([][4]byte, 0, b.Dx()*b.Dy())
+	freq := [4][]int{make([]int, 256+24), make([]int, 256), make([]int, 256), make([]int, 256)}
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := nrgba(img, x, y)
+			p := [4]byte{c.G, c.R - c.G, c.B - c.G, c.A}
+			for i, v := range p {
+				freq[i][v]++
+			}
+			opaque = opaque && c.A == 0xff
+			pix = append(pix, p)
+		}
+	}
+	bw := new(bitWriter)
+	bw.write(0x2f, 8)
+	bw.write(uint32(b.Dx()-1), 14)
+	bw.write(uint32(b.Dy()-1), 14)
+	if opaque {
+		bw.write(0, 1)
+	} else {
+		bw.write(1, 1)
+	}
+	bw.write(0, 3) // version
+	bw.write(1, 1) // transform present
+	bw.write(2, 2) // subtract green
+	bw.write(0, 1) // no further transforms
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // no meta prefix codes
+	var lengths [4][]int
+	var codes [4][]uint32
+	for i := range freq {
+		lengths[i], codes[i] = bw.writePrefixCode(freq[i])
+	}
+	bw.writePrefixCode(make([]int, 40)) // distance, unused
+	for _, p := range pix {
+		for i, v := range p {
+			bw.write(codes[i][v], uint(lengths[i][v]))
+		}
+	}
+	data := bw.bytes()
+	pad := len(data) % 2
+	hdr := make([]byte, 20)
+	copy(hdr[0:], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(12+len(data)+pad))
+	copy(hdr[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(len(data)))
+	data = append(hdr, data...)
+	if pad == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// nrgba returns the pixel of img at (x, y) without premultiplied alpha. The color of
+// translucent NYCbCrA pixels is converted directly, avoiding the rounding of premultiplication.
+func nrgba(img image.Image, x, y int) color.NRGBA {
+	if m, ok := img.(*image.NYCbCrA); ok {
+		c := m.NYCbCrAAt(x, y)
+		r, g, b := color.YCbCrToRGB(c.Y, c.Cb, c.Cr)
+		return color.NRGBA{R: r, G: g, B: b, A: c.A}
+	}
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+// bitWriter packs values into bytes least significant bit first, as WebP requires.
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+// write writes the n low bits of v.
+func (b *bitWriter) write(v uint32, n uint) {
+	b.acc |= uint64(v) << b.n
+	b.n += n
+	for b.n >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.n -= 8
+	}
+}
+
+// bytes returns the bits written, padded with zeros to a whole number of bytes.
+func (b *bitWriter) bytes() []byte {
+	if b.n > 0 {
+		return append(b.buf, byte(b.acc))
+	}
+	return b.buf
+}
+
+// writePrefixCode writes a Huffman code for symbols with frequencies freq and returns the
+// code length and bit-reversed code of each symbol, ready to be written. A code with fewer than
+// two symbols is written as a simple code whose symbol takes no bits.
+func (b *bitWriter) writePrefixCode(freq []int) ([]int, []uint32) {
+	var used []int
+	for s, f := range freq {
+		if f > 0 {
+			used = append(used, s)
+		}
+	}
+	lengths := make([]int, len(freq))
+	if len(used) < 2 {
+		s := 0
+		if len(used) == 1 {
+			s = used[0]
+		}
+		b.write(1, 1) // simple code
+		b.write(0, 1) // one symbol
+		if s < 2 {
+			b.write(0, 1)
+			b.write(uint32(s), 1)
+		} else {
+			b.write(1, 1)
+			b.write(uint32(s), 8)
+		}
+		return lengths, make([]uint32, len(freq))
+	}
+	lengths = huffmanLengths(freq, 15)
+	codes := canonicalCodes(lengths)
+	// The code lengths are themselves Huffman coded, using lengths up to 7
+	clFreq := make([]int, len(codeLengthCodeOrder))
+	for _, l := range lengths {
+		clFreq[l]++
+	}
+	clUsed := 0
+	for _, f := range clFreq {
+		if f > 0 {
+			clUsed++
+		}
+	}
+	if clUsed == 1 {
+		// A code needs two symbols to take any bits; add an unused one
+		if clFreq[0] == 0 {
+			clFreq[0] = 1
+		} else {
+			clFreq[1] = 1
+		}
+	}
+	clLengths := huffmanLengths(clFreq, 7)
+	clCodes := canonicalCodes(clLengths)
+	n := len(codeLengthCodeOrder)
+	for n > 4 && clLengths[codeLengthCodeOrder[n-1]] == 0 {
+		n--
+	}
+	b.write(0, 1) // normal code
+	b.write(uint32(n-4), 4)
+	for _, s := range codeLengthCodeOrder[:n] {
+		b.write(uint32(clLengths[s]), 3)
+	}
+	b.write(0, 1) // lengths given for every symbol
+	for _, l := range lengths {
+		b.write(clCodes[l], uint(clLengths[l]))
+	}
+	return lengths, codes
+}
+
+// huffmanLengths returns the code lengths of a Huffman code for symbols with frequencies freq,
+// with no length above limit. Frequencies are repeatedly halved until the limit is met.
+func huffmanLengths(freq []int, limit int) []int {
+	f := append([]int(nil), freq...)
+	for {
+		lengths := huffmanTree(f)
+		max := 0
+		for _, l := range lengths {
+			if l > max {
+				max = l
+			}
+		}
+		if max <= limit {
+			return lengths
+		}
+		for s := range f {
+			if f[s] > 0 {
+				f[s] = (f[s] + 1) / 2
+			}
+		}
+	}
+}
+
+// huffmanTree returns the depth of each symbol in a Huffman tree for frequencies freq, using
+// the two-queue construction. Symbols with zero frequency have depth zero.
+func huffmanTree(freq []int) []int {
+	type node struct {
+		weight int
+		parent int
+	}
+	var nodes []node
+	var leaves []int // node index of each used symbol, in order of increasing frequency
+	var symbols []int
+	for s, f := range freq {
+		if f > 0 {
+			symbols = append(symbols, s)
+		}
+	}
+	sort.SliceStable(symbols, func(i, j int) bool { return freq[symbols[i]] < freq[symbols[j]] })
+	for _, s := range symbols {
+		leaves = append(leaves, len(nodes))
+		nodes = append(nodes, node{weight: freq[s], parent: -1})
+	}
+	var internal []int
+	li, ii := 0, 0
+	pop := func() int {
+		if li < len(leaves) && (ii >= len(internal) || nodes[leaves[li]].weight <= nodes[internal[ii]].weight) {
+			li++
+			return leaves[li-1]
+		}
+		ii++
+		return internal[ii-1]
+	}
+	for (len(leaves)-li)+(len(internal)-ii) > 1 {
+		a, c := pop(), pop()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[c].weight, parent: -1})
+		nodes[a].parent = len(nodes) - 1
+		nodes[c].parent = len(nodes) - 1
+		internal = append(internal, len(nodes)-1)
+	}
+	lengths := make([]int, len(freq))
+	for k, s := range symbols {
+		for n := leaves[k]; nodes[n].parent >= 0; n = nodes[n].parent {
+			lengths[s]++
+		}
+	}
+	return lengths
+}
+
+// canonicalCodes returns the canonical Huffman codes for code lengths, bit-reversed so that
+// they can be written least significant bit first.
+func canonicalCodes(lengths []int) []uint32 {
+	var count [16]uint32
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [16]uint32
+	code := uint32(0)
+	for l := 1; l < len(next); l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint32, len(lengths))
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		for k := 0; k < l; k++ {
+			codes[s] = codes[s]<<1 | c>>k&1
+		}
+	}
+	return codes
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "filter", Summary: "filter and color adjust a stream", Run: runFilter})
+}
+
+type filterOptions struct {
+	inFile       string
+	outFile      string
+	median       int
+	blur         float64
+	sharpen      float64
+	sharpenSigma float64
+	threshold    int
+	brightness   int
+	contrast     float64
+	saturation   float64
+	levels       string
+	adjust       y4m.Adjustment
+	mono         bool
+	neutral      bool
+	swapChroma   bool
+}
+
+func runFilter(fs *flag.FlagSet, args []string) error {
+	o := new(filterOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.IntVar(&o.median, "median", 0, "radius of the median filter; 0 for none")
+	fs.Float64Var(&o.blur, "blur", 0, "standard deviation of the Gaussian blur in luma samples; 0 for none")
+	fs.Float64Var(&o.sharpen, "sharpen", 0, "amount of unsharp masking applied to luma; 0 for none")
+	fs.Float64Var(&o.sharpenSigma, "sigma", 1, "standard deviation of the unsharp mask blur")
+	fs.IntVar(&o.threshold, "threshold", 0, "largest luma difference left unsharpened")
+	fs.IntVar(&o.brightness, "brightness", 0, "offset added to luma")
+	fs.Float64Var(&o.contrast, "contrast", 1, "luma gain about mid gray")
+	fs.Float64Var(&o.saturation, "saturation", 1, "chroma gain; 0 for grayscale")
+	fs.StringVar(&o.levels, "levels", "", "luma black and white points, BLACK:WHITE[:OUTBLACK:OUTWHITE]")
+	fs.BoolVar(&o.mono, "mono", false, "drop the chroma planes, writing a luma-only stream")
+	fs.BoolVar(&o.neutral, "neutral", false, "fill the chroma planes with 128, removing all color")
+	fs.BoolVar(&o.swapChroma, "swapuv", false, "exchange the Cb and Cr planes")
+	err := parse(fs, args, &o.inFile, &o.outFile)
+	if err != nil {
+		return err
+	}
+	return o.filter()
+}
+
+func (o *filterOptions) filter() error {
+	in, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	err = o.setAdjustment(in.ColorRange)
+	if err != nil {
+		return err
+	}
+	out, err := createLike(o.outFile, in)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if o.mono {
+		out.ToMono()
+	}
+	err = out.WriteHeader()
+	if err != nil {
+		return err
+	}
+	for {
+		frame, err := in.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		err = o.apply(frame)
+		if err != nil {
+			return err
+		}
+		err = out.WriteFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return out.Sync()
+}
+
+// setAdjustment sets the color adjustment from the options. Black and white points that are
+// not given are those of color range r.
+func (o *filterOptions) setAdjustment(r y4m.ColorRange) error {
+	o.adjust = y4m.NewAdjustment(r)
+	o.adjust.Brightness = o.brightness
+	o.adjust.Contrast = o.contrast
+	o.adjust.Saturation = o.saturation
+	if o.levels == "" {
+		return nil
+	}
+	a := &o.adjust
+	n, _ := fmt.Sscanf(o.levels, "%d:%d:%d:%d", &a.BlackIn, &a.WhiteIn, &a.BlackOut, &a.WhiteOut)
+	if n != 2 && n != 4 {
+		return fmt.Errorf("could not parse levels %q", o.levels)
+	}
+	return nil
+}
+
+// apply applies the selected filters to a frame: the median filter first, to remove impulse
+// noise before it is spread by the others, then the blur, the unsharp mask and the color
+// adjustment. For mono output, chroma is dropped before filtering, and otherwise the chroma
+// planes are swapped or neutralized first.
+func (o *filterOptions) apply(frame *y4m.Frame) error {
+	if o.mono {
+		frame.ToMono()
+	}
+	if o.swapChroma {
+		frame.SwapChroma()
+	}
+	if o.neutral {
+		for _, i := range []int{y4m.PlaneCb, y4m.PlaneCr} {
+			err := frame.FillPlane(i, 128)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err := frame.Median(o.median)
+	if err != nil {
+		return err
+	}
+	err = frame.GaussianBlur(o.blur)
+	if err != nil {
+		return err
+	}
+	err = frame.UnsharpMask(o.sharpenSigma, o.sharpen, o.threshold)
+	if err != nil {
+		return err
+	}
+	return frame.Adjust(o.adjust)
+}
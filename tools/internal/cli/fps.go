@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "fps", Summary: "change the frame rate of a stream", Run: runFps})
+}
+
+type fpsOptions struct {
+	inFile  string
+	outFile string
+	rate    string
+	mode    string
+}
+
+func runFps(fs *flag.FlagSet, args []string) error {
+	o := new(fpsOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.StringVar(&o.rate, "r", "", "output frame rate N:D, decimal or name such as ntsc")
+	fs.StringVar(&o.mode, "m", "header", "mode {header, nearest, blend}")
+	err := parse(fs, args, &o.inFile, &o.outFile, &o.rate)
+	if err != nil {
+		return err
+	}
+	return o.fps()
+}
+
+func (o *fpsOptions) fps() error {
+	rate, err := parseRate(o.rate)
+	if err != nil {
+		return err
+	}
+	var r *y4m.Retimer
+	sIn, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer sIn.Close()
+	switch o.mode {
+	case "header":
+	case "nearest":
+		r, err = y4m.NewRetimer(sIn.FrameRate, rate, y4m.RetimeNearest)
+	case "blend":
+		r, err = y4m.NewRetimer(sIn.FrameRate, rate, y4m.RetimeBlend)
+	default:
+		return fmt.Errorf("unrecognized mode %q", o.mode)
+	}
+	if err != nil {
+		return err
+	}
+	sOut, err := createLike(o.outFile, sIn)
+	if err != nil {
+		return err
+	}
+	defer sOut.Close()
+	sOut.FrameRate = rate
+	err = sOut.WriteHeader()
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		// Only the declared rate changes, so the frames are copied unparsed
+		err = sIn.Trim(sOut, 0, -1)
+		if err != nil {
+			return err
+		}
+		return sOut.Sync()
+	}
+	for {
+		frame, err := sIn.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		frames, err := r.Push(frame)
+		if err != nil {
+			return err
+		}
+		err = writeFrames(sOut, frames)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeFrames(sOut, r.Flush())
+	if err != nil {
+		return err
+	}
+	return sOut.Sync()
+}
+
+func writeFrames(s *y4m.Stream, frames []*y4m.Frame) error {
+	for _, f := range frames {
+		err := s.WriteFrame(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+
+	_ "golang.org/x/image/tiff"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "fromimg", Summary: "create a stream from JPEG/PNG/TIFF images", Run: runFromImg})
+}
+
+type fromImgOptions struct {
+	pattern    string
+	outFile    string
+	rate       string
+	chroma     string
+	colorRange string
+}
+
+func runFromImg(fs *flag.FlagSet, args []string) error {
+	o := new(fromImgOptions)
+	fs.StringVar(&o.pattern, "i", "", "input file pattern, such as \"aspen*.png\"")
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.StringVar(&o.rate, "r", "25:1", "frame rate N:D, decimal or name such as ntsc")
+	fs.StringVar(&o.chroma, "c", "420jpeg", "chroma format")
+	fs.StringVar(&o.colorRange, "range", "full", "sample range {full, limited}")
+	err := parse(fs, args, &o.pattern, &o.outFile)
+	if err != nil {
+		return err
+	}
+	return o.fromImg()
+}
+
+func (o *fromImgOptions) fromImg() error {
+	rate, err := parseRate(o.rate)
+	if err != nil {
+		return err
+	}
+	var r y4m.ColorRange
+	switch o.colorRange {
+	case "full":
+		r = y4m.ColorRangeFull
+	case "limited":
+		r = y4m.ColorRangeLimited
+	default:
+		return fmt.Errorf("unrecognized range %q", o.colorRange)
+	}
+	// Glob sorts the names, so zero-padded frame numbers such as y4grab writes are in order
+	names, err := filepath.Glob(o.pattern)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no files match %q", o.pattern)
+	}
+	first, err := readImage(names[0])
+	if err != nil {
+		return err
+	}
+	b := first.Bounds()
+	s, err := y4m.NewStream(o.outFile, b.Dx(), b.Dy())
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	err = s.SetChroma(o.chroma)
+	if err != nil {
+		return err
+	}
+	s.FrameRate = rate
+	s.Interlacing = "p"
+	s.SampleAspectRatio = &y4m.Ratio{N: 1, D: 1}
+	s.ColorRange = r
+	err = s.WriteHeader()
+	if err != nil {
+		return err
+	}
+	img := first
+	for k, name := range names {
+		if k > 0 {
+			img, err = readImage(name)
+			if err != nil {
+				return err
+			}
+			if img.Bounds().Dx() != s.Width || img.Bounds().Dy() != s.Height {
+				return fmt.Errorf("%s: size %dx%d differs from %dx%d of %s", name,
+					img.Bounds().Dx(), img.Bounds().Dy(), s.Width, s.Height, names[0])
+			}
+		}
+		f, err := y4m.FrameFromImageRange(img, o.chroma, r)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		err = s.WriteFrame(f)
+		if err != nil {
+			return err
+		}
+	}
+	return s.Sync()
+}
+
+// readImage decodes the JPEG, PNG or TIFF image in the named file.
+func readImage(name string) (image.Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return img, nil
+}
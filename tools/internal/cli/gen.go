@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "gen", Summary: "generate a test pattern stream", Run: runGen})
+}
+
+type genOptions struct {
+	outFile  string
+	pattern  string
+	width    int
+	height   int
+	chroma   string
+	rate     string
+	duration string
+	size     int
+	seed     int64
+}
+
+func runGen(fs *flag.FlagSet, args []string) error {
+	o := new(genOptions)
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.StringVar(&o.pattern, "p", "bars", "pattern {bars, gradient, vgradient, zoneplate, checkerboard, shapes, noise}")
+	fs.IntVar(&o.width, "w", 640, "width")
+	fs.IntVar(&o.height, "h", 480, "height")
+	fs.StringVar(&o.chroma, "c", "420jpeg", "chroma format")
+	fs.StringVar(&o.rate, "r", "25:1", "frame rate N:D, decimal or name such as ntsc")
+	fs.StringVar(&o.duration, "d", "1", "duration [[HH:]MM:]SS[.fff]")
+	fs.IntVar(&o.size, "size", 16, "(checkerboard only) square size")
+	fs.Int64Var(&o.seed, "seed", 1, "(noise only) random seed")
+	err := parse(fs, args, &o.outFile)
+	if err != nil {
+		return err
+	}
+	return o.gen()
+}
+
+func (o *genOptions) gen() error {
+	rate, err := parseRate(o.rate)
+	if err != nil {
+		return err
+	}
+	duration, err := parseTimestamp(o.duration)
+	if err != nil {
+		return err
+	}
+	frame, err := o.patternFunc()
+	if err != nil {
+		return err
+	}
+	s, err := y4m.NewStream(o.outFile, o.width, o.height)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	err = s.SetChroma(o.chroma)
+	if err != nil {
+		return err
+	}
+	s.FrameRate = rate
+	s.Interlacing = "p"
+	s.SampleAspectRatio = &y4m.Ratio{N: 1, D: 1}
+	if o.pattern != "noise" {
+		s.ColorRange = y4m.ColorRangeLimited
+	}
+	count, err := framesBefore(s, duration)
+	if err != nil {
+		return err
+	}
+	err = s.WriteHeader()
+	if err != nil {
+		return err
+	}
+	for k := 0; k < count; k++ {
+		f, err := frame(k)
+		if err != nil {
+			return err
+		}
+		err = s.WriteFrame(f)
+		if err != nil {
+			return err
+		}
+	}
+	return s.Sync()
+}
+
+// patternFunc returns a function generating frame k of the selected pattern. Static patterns
+// are generated once and repeated.
+func (o *genOptions) patternFunc() (func(k int) (*y4m.Frame, error), error) {
+	w, h, c := o.width, o.height, o.chroma
+	static := func(f *y4m.Frame, err error) (func(int) (*y4m.Frame, error), error) {
+		if err != nil {
+			return nil, err
+		}
+		return func(int) (*y4m.Frame, error) { return f, nil }, nil
+	}
+	switch o.pattern {
+	case "bars":
+		return static(y4m.ColorBars(w, h, c))
+	case "gradient":
+		return static(y4m.Gradient(w, h, c))
+	case "checkerboard":
+		return static(y4m.Checkerboard(w, h, c, o.size))
+	case "vgradient", "shapes":
+		var src y4m.FrameSource
+		var err error
+		if o.pattern == "shapes" {
+			src, err = y4m.NewShapeSource(w, h, c, math.MaxInt32)
+		} else {
+			src, err = y4m.NewRampSource(w, h, c, true, math.MaxInt32)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(int) (*y4m.Frame, error) { return src.Next() }, nil
+	case "zoneplate", "noise":
+		// Check the geometry before any output is written
+		_, err := y4m.NewFrame(w, h, c)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized pattern %q", o.pattern)
+	}
+	if o.pattern == "zoneplate" {
+		return func(k int) (*y4m.Frame, error) { return y4m.ZonePlate(w, h, c, k) }, nil
+	}
+	r := rand.New(rand.NewSource(o.seed))
+	return func(int) (*y4m.Frame, error) { return y4m.RandomFrame(r, w, h, c) }, nil
+}
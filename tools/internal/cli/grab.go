@@ -0,0 +1,366 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "grab", Summary: "save frames as images", Run: runGrab})
+}
+
+type grabOptions struct {
+	inputFile     string
+	outputFile    string
+	format        string
+	startFrame    int
+	frameCount    int
+	frameRanges   string
+	every         int
+	jpegQuality   int
+	compressTIFF  bool
+	predictorTIFF bool
+	threads       int
+	depth         int
+	scenes        bool
+	sceneCut      float64
+	square        bool
+	kernel        string
+}
+
+func runGrab(fs *flag.FlagSet, args []string) error {
+	o := new(grabOptions)
+	fs.StringVar(&o.inputFile, "i", "", "input filename")
+	fs.StringVar(&o.outputFile, "o", "", "output filename")
+	fs.StringVar(&o.format, "f", "jpeg", "image format {\"jpeg\", \"png\", \"tiff\", \"ppm\", \"pgm\", \"bmp\", \"webp\"}")
+	fs.IntVar(&o.startFrame, "s", 1, "start frame")
+	fs.IntVar(&o.frameCount, "n", 1, "number of frames to grab")
+	fs.StringVar(&o.frameRanges, "frames", "", "frames to grab, e.g. \"10-250:5,300\"; overrides -s and -n")
+	fs.IntVar(&o.every, "every", 0, "grab every nth frame, starting with the first; overrides -s and -n")
+	fs.BoolVar(&o.scenes, "scenes", false, "grab the middle frame of every shot; overrides -s, -n, -frames and -every")
+	fs.Float64Var(&o.sceneCut, "cut", 0.3, "(-scenes only) scene change threshold [0-1]")
+	fs.IntVar(&o.jpegQuality, "jq", 75, "(JPEG only) quality [0-100]")
+	fs.BoolVar(&o.compressTIFF, "tc", false, "(TIFF only) use deflate compression")
+	fs.BoolVar(&o.predictorTIFF, "tp", false, "(TIFF only) use differencing predictor")
+	fs.IntVar(&o.threads, "threads", runtime.NumCPU(), "number of images to encode concurrently")
+	fs.IntVar(&o.depth, "depth", 8, "(PNG and TIFF only) bits per sample {8, 16}")
+	fs.BoolVar(&o.square, "square", false, "resample frames to square pixels using the sample aspect ratio")
+	fs.StringVar(&o.kernel, "k", "bicubic", "(-square only) kernel {nearest, bilinear, bicubic, lanczos}")
+	err := parse(fs, args, &o.inputFile)
+	if err != nil {
+		return err
+	}
+	return o.grab()
+}
+
+func (o *grabOptions) grab() error {
+	if o.depth != 8 && o.depth != 16 {
+		return fmt.Errorf("unsupported bit depth %d", o.depth)
+	}
+	if o.depth == 16 && o.format != "png" && o.format != "tiff" {
+		return fmt.Errorf("16-bit samples are only supported for PNG and TIFF images")
+	}
+	kernel, err := y4m.ParseKernel(o.kernel)
+	if err != nil {
+		return err
+	}
+	// Open file
+	s, err := y4m.Open(o.inputFile)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	frames, err := o.selection(s)
+	if err != nil {
+		return err
+	}
+	name := o.filenameFormat(o.inputFile, o.outputFile, frames)
+	// Frames are decoded in order and encoded by a pool of workers
+	if o.threads < 1 {
+		o.threads = 1
+	}
+	jobs := make(chan grabJob, o.threads)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var encodeErr error
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return encodeErr != nil
+	}
+	for k := 0; k < o.threads; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				err := o.writeFile(j, name, len(frames))
+				if err != nil {
+					mu.Lock()
+					if encodeErr == nil {
+						encodeErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	// Skip to and grab each selected frame
+	err = func() error {
+		defer close(jobs)
+		n := 1
+		for k, f := range frames {
+			for ; n < f; n++ {
+				err := s.SkipFrame()
+				if err == io.EOF {
+					return fmt.Errorf("Reached end of stream at frame %d. %d of %d frames grabbed.",
+						n-1, k, len(frames))
+				} else if err != nil {
+					return err
+				}
+			}
+			frame, err := s.ParseFrame()
+			if err == io.EOF {
+				return fmt.Errorf("Reached end of stream at frame %d. %d of %d frames grabbed.",
+					n-1, k, len(frames))
+			} else if err != nil {
+				return err
+			}
+			n++
+			if w, h := s.SquarePixelSize(); o.square && w != frame.Width {
+				err = frame.Resize(w, h, kernel)
+				if err != nil {
+					return err
+				}
+			}
+			j := grabJob{frame: frame, r: s.ColorRange, n: f}
+			if o.format != "ppm" && o.format != "pgm" {
+				j.img, err = o.image(frame, s.ColorRange)
+				if err != nil {
+					return err
+				}
+			}
+			if failed() {
+				return nil
+			}
+			jobs <- j
+		}
+		return nil
+	}()
+	wg.Wait()
+	if encodeErr != nil {
+		return encodeErr
+	}
+	return err
+}
+
+// grabJob is a frame to be written as image file number n, counting from 1. PPM and PGM
+// images are written from the frame by the library writers; other formats are encoded from
+// img, the frame converted to an image by grabOptions.image.
+type grabJob struct {
+	frame *y4m.Frame
+	r     y4m.ColorRange // range of the frame's samples
+	img   image.Image
+	n     int
+}
+
+// image converts frame, whose samples use range r, to an image of the selected bit depth.
+// 16-bit images are converted with the BT.601 matrix, as 8-bit images are.
+func (o *grabOptions) image(frame *y4m.Frame, r y4m.ColorRange) (image.Image, error) {
+	if o.depth == 8 {
+		return frame.ImageRange(r)
+	}
+	if frame.Chroma == "mono" {
+		return frame.ToGray16(r), nil
+	}
+	return frame.ToNRGBA64(y4m.BT601, r)
+}
+
+// selection returns the numbers of the frames of stream s to grab, counting from 1, in
+// increasing order. It fails if no frames are selected.
+func (o *grabOptions) selection(s *y4m.Stream) ([]int, error) {
+	if o.scenes {
+		return sceneFrames(s, o.sceneCut)
+	}
+	if o.frameRanges == "" && o.every <= 0 {
+		if o.frameCount < 1 {
+			return nil, fmt.Errorf("no frames selected: number of frames must be at least 1")
+		}
+		var frames []int
+		for k := 0; k < o.frameCount; k++ {
+			frames = append(frames, o.startFrame+k)
+		}
+		return frames, nil
+	}
+	count, err := s.CountFrames()
+	if err != nil {
+		return nil, err
+	}
+	if o.frameRanges == "" {
+		return parseFrameRanges(fmt.Sprintf("1-:%d", o.every), count)
+	}
+	return parseFrameRanges(o.frameRanges, count)
+}
+
+// sceneFrames returns the number of the middle frame of each shot of stream s, counting from
+// 1, with scene changes detected at threshold cut. The stream is left at its first frame.
+func sceneFrames(s *y4m.Stream, cut float64) ([]int, error) {
+	d := y4m.NewSceneDetector(cut)
+	for {
+		f, err := s.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		d.Push(f)
+	}
+	var frames []int
+	for _, r := range d.Scenes() {
+		frames = append(frames, (r.Start+r.End)/2+1)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("stream has no frames")
+	}
+	return frames, s.ToFirstFrame()
+}
+
+// parseFrameRanges parses a comma separated list of frame numbers and ranges of the form
+// first-last[:step], where an omitted last frame is the last of the count frames in the
+// stream. It returns the selected frame numbers in increasing order, without duplicates.
+func parseFrameRanges(ranges string, count int) ([]int, error) {
+	selected := map[int]bool{}
+	for _, r := range strings.Split(ranges, ",") {
+		spec, step := r, 1
+		if n := strings.IndexByte(r, ':'); n >= 0 {
+			var err error
+			spec = r[:n]
+			step, err = strconv.Atoi(r[n+1:])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in frame range %q", r)
+			}
+		}
+		first, last := spec, spec
+		if n := strings.IndexByte(spec, '-'); n >= 0 {
+			first, last = spec[:n], spec[n+1:]
+		}
+		a, err := strconv.Atoi(first)
+		if err != nil || a < 1 {
+			return nil, fmt.Errorf("invalid frame range %q", r)
+		}
+		b := count
+		if last != "" {
+			b, err = strconv.Atoi(last)
+			if err != nil || b < a {
+				return nil, fmt.Errorf("invalid frame range %q", r)
+			}
+		}
+		for f := a; f <= b; f += step {
+			selected[f] = true
+		}
+	}
+	var frames []int
+	for f := range selected {
+		frames = append(frames, f)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames selected by %q", ranges)
+	}
+	sort.Ints(frames)
+	return frames, nil
+}
+
+func (o *grabOptions) filenameFormat(in, out string, frames []int) string {
+	var filePrefix, fileSuffix string
+	if out == "" {
+		// Use input file to derive output filename
+		extensions := map[string]string{
+			"jpeg": "jpg",
+			"tiff": "tif",
+			"png":  "png",
+			"ppm":  "ppm",
+			"pgm":  "pgm",
+			"bmp":  "bmp",
+			"webp": "webp",
+		}
+		basename := filepath.Base(in)
+		fileSuffix = "." + extensions[strings.ToLower(o.format)]
+		filePrefix = strings.TrimSuffix(basename, filepath.Ext(basename))
+	} else {
+		dir, file := filepath.Split(out)
+		fileSuffix = filepath.Ext(file)
+		filePrefix = dir + strings.TrimSuffix(file, fileSuffix)
+	}
+	var formatString string
+	if len(frames) <= 1 {
+		formatString = filePrefix + fileSuffix
+	} else {
+		leadingZeros := int(math.Log10(float64(frames[len(frames)-1]))) + 1
+		formatString = filePrefix + "%0" + strconv.Itoa(leadingZeros) + "d" + fileSuffix
+	}
+	return formatString
+}
+
+func (o *grabOptions) writeFile(j grabJob, filenameFormat string, count int) error {
+	var f *os.File
+	var err error
+	if count > 1 {
+		f, err = os.Create(fmt.Sprintf(filenameFormat, j.n))
+	} else {
+		f, err = os.Create(filenameFormat)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	img := j.img
+	switch o.format {
+	case "jpeg":
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: o.jpegQuality})
+	case "png":
+		err = png.Encode(f, img)
+	case "tiff":
+		compressionType := tiff.Uncompressed
+		if o.compressTIFF {
+			compressionType = tiff.Deflate
+		}
+		options := &tiff.Options{
+			Compression: compressionType,
+			Predictor:   o.predictorTIFF,
+		}
+		err = tiff.Encode(f, img, options)
+	case "ppm":
+		err = j.frame.WritePPM(f, y4m.BT601, j.r)
+	case "pgm":
+		// Images hold full range samples, so limited range luma is expanded as for other formats
+		frame := j.frame
+		if j.r == y4m.ColorRangeLimited {
+			frame = frame.Copy()
+			frame.ConvertRange(y4m.ColorRangeLimited, y4m.ColorRangeFull)
+		}
+		err = frame.WritePGM(f)
+	case "bmp":
+		err = bmp.Encode(f, img)
+	case "webp":
+		err = encodeWebP(f, img)
+	default:
+		err = fmt.Errorf("Unrecognized image format -- %s", o.format)
+	}
+	return err
+}
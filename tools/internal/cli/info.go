@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "info", Summary: "print stream information", Run: runInfo})
+}
+
+// streamInfo holds the information printed by the info command with -json.
+type streamInfo struct {
+	File               string      `json:"file"`
+	Width              int         `json:"width"`
+	Height             int         `json:"height"`
+	FrameRate          string      `json:"frameRate"`
+	Interlacing        string      `json:"interlacing"`
+	SampleAspectRatio  string      `json:"sampleAspectRatio"`
+	DisplayAspectRatio string      `json:"displayAspectRatio,omitempty"`
+	Chroma             string      `json:"chroma"`
+	YSCSS              string      `json:"yscss,omitempty"`
+	ColorRange         string      `json:"colorRange,omitempty"`
+	MasteringDisplay   string      `json:"masteringDisplay,omitempty"`
+	MaxCLL             int         `json:"maxCLL,omitempty"`
+	MaxFALL            int         `json:"maxFALL,omitempty"`
+	Metadata           []string    `json:"metadata"`
+	Frames             int         `json:"frames"`
+	Duration           *float64    `json:"duration"` // seconds; null if the frame rate is unknown
+	FrameSize          int64       `json:"frameSize"`
+	DataSize           int64       `json:"dataSize"`
+	FileSize           int64       `json:"fileSize"`
+	FrameList          []frameInfo `json:"frameList,omitempty"`
+}
+
+// frameInfo holds the information about one frame printed by the info command with -frames.
+type frameInfo struct {
+	Frame    int      `json:"frame"` // frame number, counting from 1
+	Offset   int64    `json:"offset"`
+	I        string   `json:"i,omitempty"`
+	Metadata []string `json:"metadata"`
+}
+
+func runInfo(fs *flag.FlagSet, args []string) error {
+	inFile := fs.String("i", "", "input file")
+	asJSON := fs.Bool("json", false, "print the information as JSON")
+	perFrame := fs.Bool("frames", false, "list the offset, I field and tags of every frame")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [-json] [-frames] [-i] file\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	if *inFile == "" && fs.NArg() > 0 {
+		*inFile = fs.Arg(0)
+	}
+	if *inFile == "" {
+		fs.Usage()
+		return errUsage
+	}
+	s, err := y4m.Open(*inFile)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	nFrames, err := s.CountFrames()
+	if err != nil {
+		return err
+	}
+	var frames []frameInfo
+	if *perFrame {
+		frames, err = listFrames(s)
+		if err != nil {
+			return err
+		}
+	}
+	if *asJSON {
+		return printInfoJSON(*inFile, s, nFrames, frames)
+	}
+	s.PrintHeaderInfo()
+	fmt.Printf("Frames:\n  %d\n", nFrames)
+	d, err := s.Duration(nFrames)
+	switch {
+	case errors.Is(err, y4m.ErrUnknownFrameRate):
+		fmt.Printf("Duration:\n  unknown (frame rate not specified)\n")
+	case err != nil:
+		return err
+	default:
+		fmt.Printf("Duration:\n  %s\n", d.Round(time.Microsecond))
+	}
+	if *perFrame {
+		fmt.Println("Frame list (frame, offset, I field, tags):")
+		for _, f := range frames {
+			i := f.I
+			if i == "" {
+				i = "-"
+			}
+			fmt.Printf("  %d %d %s", f.Frame, f.Offset, i)
+			for _, m := range f.Metadata {
+				fmt.Printf(" X%s", m)
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// listFrames returns the offset, I field and tags of every frame of stream s.
+func listFrames(s *y4m.Stream) ([]frameInfo, error) {
+	var frames []frameInfo
+	r := s.NewReader()
+	for n := 1; ; n++ {
+		offset := r.Offset()
+		h, err := r.NextFrameHeader()
+		if err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return nil, err
+		}
+		f := frameInfo{Frame: n, Offset: offset, Metadata: append([]string{}, h.Metadata...)}
+		if h.I != nil {
+			f.I = h.I.String()
+		}
+		frames = append(frames, f)
+	}
+}
+
+// printInfoJSON prints the information about stream s, read from the named file and holding
+// nFrames frames, as JSON, including the frame list if any.
+func printInfoJSON(name string, s *y4m.Stream, nFrames int, frames []frameInfo) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	info := streamInfo{
+		File:              name,
+		Width:             s.Width,
+		Height:            s.Height,
+		FrameRate:         s.FrameRate.String(),
+		Interlacing:       s.Interlacing,
+		SampleAspectRatio: s.SampleAspectRatio.String(),
+		Chroma:            s.Chroma,
+		YSCSS:             s.YSCSS,
+		MaxCLL:            s.MaxCLL,
+		MaxFALL:           s.MaxFALL,
+		Metadata:          append([]string{}, s.Metadata...),
+		Frames:            nFrames,
+		FrameSize:         s.FrameImageDataSize(),
+		DataSize:          int64(nFrames) * s.FrameImageDataSize(),
+		FileSize:          fi.Size(),
+		FrameList:         frames,
+	}
+	if s.ColorRange != y4m.ColorRangeUnspecified {
+		info.ColorRange = s.ColorRange.String()
+	}
+	if s.MasteringDisplay != nil {
+		info.MasteringDisplay = s.MasteringDisplay.String()
+	}
+	if dar := s.DisplayAspectRatio(); dar != nil {
+		info.DisplayAspectRatio = dar.String()
+	}
+	if d, err := s.Duration(nFrames); err == nil {
+		seconds := d.Seconds()
+		info.Duration = &seconds
+	}
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	return e.Encode(info)
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "meta", Summary: "list, add and remove X metadata tags", Run: runMeta})
+}
+
+type metaOptions struct {
+	inFile  string
+	outFile string
+	set     stringList
+	del     stringList
+	frames  bool
+}
+
+func runMeta(fs *flag.FlagSet, args []string) error {
+	o := new(metaOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.StringVar(&o.outFile, "o", "", "output file; empty to rewrite the input file")
+	fs.Var(&o.set, "set", "set tag KEY=value; may be repeated")
+	fs.Var(&o.del, "del", "remove tags with KEY; may be repeated")
+	fs.BoolVar(&o.frames, "frames", false, "also list or edit the tags of every frame header")
+	err := parse(fs, args, &o.inFile)
+	if err != nil {
+		return err
+	}
+	if len(o.set) == 0 && len(o.del) == 0 {
+		return o.list()
+	}
+	return o.edit()
+}
+
+// list prints the stream tags and, with -frames, the tags of each frame that has any.
+func (o *metaOptions) list() error {
+	s, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	fmt.Println("stream:")
+	for _, t := range s.Tags() {
+		fmt.Printf("  %s\n", t)
+	}
+	if !o.frames {
+		return nil
+	}
+	for n := 1; ; n++ {
+		frame, err := s.ParseFrame()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if frame.Header != nil && len(frame.Header.Metadata) > 0 {
+			fmt.Printf("frame %d:\n", n)
+			for _, m := range frame.Header.Metadata {
+				fmt.Printf("  %s\n", m)
+			}
+		}
+	}
+}
+
+// edit writes the input stream with the tags changed to the output file, or to a temporary
+// file that then replaces the input file.
+func (o *metaOptions) edit() error {
+	type tag struct{ key, value string }
+	var set []tag
+	for _, t := range o.set {
+		n := strings.IndexByte(t, '=')
+		if n < 0 {
+			return fmt.Errorf("tag %q is not of the form KEY=value", t)
+		}
+		set = append(set, tag{t[:n], t[n+1:]})
+	}
+	sIn, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer sIn.Close()
+	outFile := o.outFile
+	if outFile == "" {
+		tmp, err := os.CreateTemp(filepath.Dir(o.inFile), ".y4meta-*.y4m")
+		if err != nil {
+			return err
+		}
+		tmp.Close()
+		outFile = tmp.Name()
+		defer os.Remove(outFile)
+	}
+	sOut, err := createLike(outFile, sIn)
+	if err != nil {
+		return err
+	}
+	defer sOut.Close()
+	for _, key := range o.del {
+		sOut.DeleteMetadata(key)
+	}
+	for _, t := range set {
+		err = sOut.SetMetadata(t.key, t.value)
+		if err != nil {
+			return err
+		}
+	}
+	err = sOut.WriteHeader()
+	if err != nil {
+		return err
+	}
+	for {
+		frame, err := sIn.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if o.frames {
+			for _, key := range o.del {
+				if frame.Header != nil {
+					frame.Header.DeleteMetadata(key)
+				}
+			}
+			for _, t := range set {
+				err = frame.SetMetadata(t.key, t.value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		err = sOut.WriteFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+	err = sOut.Sync()
+	if err != nil || o.outFile != "" {
+		return err
+	}
+	err = sOut.Close()
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(o.inFile)
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(outFile, fi.Mode())
+	if err != nil {
+		return err
+	}
+	return os.Rename(outFile, o.inFile)
+}
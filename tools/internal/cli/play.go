@@ -0,0 +1,355 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "play", Summary: "preview a stream in the terminal", Run: runPlay})
+}
+
+type playOptions struct {
+	inFile string
+	width  int
+	loop   bool
+}
+
+// player holds the state of a preview in the terminal.
+type player struct {
+	o      *playOptions
+	s      *y4m.Stream
+	idx    y4m.FrameIndex // index of the frames, or nil if the stream cannot seek
+	frame  *y4m.Frame     // frame displayed, if the stream cannot seek
+	n      int            // number of the frame displayed, counting from zero
+	paused bool
+	out    *bufio.Writer
+}
+
+func runPlay(fs *flag.FlagSet, args []string) error {
+	o := new(playOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file; - for standard input")
+	fs.IntVar(&o.width, "w", 0, "display width in characters; 0 for terminal width")
+	fs.BoolVar(&o.loop, "loop", false, "restart at the end of the stream")
+	err := parse(fs, args, &o.inFile)
+	if err != nil {
+		return err
+	}
+	return o.play()
+}
+
+func (o *playOptions) play() error {
+	s, err := o.openInput()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	p := &player{o: o, s: s, out: bufio.NewWriter(os.Stdout)}
+	err = p.start()
+	if err != nil {
+		return err
+	}
+	rate := 25.0
+	if r := s.FrameRate.Float64(); r > 0 {
+		rate = r
+	}
+	keys, restore := terminalKeys()
+	defer restore()
+	fmt.Fprint(p.out, "\x1b[2J\x1b[?25l")
+	defer func() {
+		fmt.Fprint(p.out, "\x1b[0m\x1b[?25h\n")
+		p.out.Flush()
+	}()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	tick := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer tick.Stop()
+	err = p.render()
+	for err == nil {
+		select {
+		case <-interrupt:
+			return nil
+		case k, ok := <-keys:
+			if !ok || k == "q" {
+				return nil
+			}
+			err = p.key(k, int(rate*5))
+		case <-tick.C:
+			if p.paused {
+				continue
+			}
+			var more bool
+			more, err = p.next()
+			if err == nil && !more {
+				if o.loop && p.idx != nil {
+					p.n = 0
+				} else {
+					p.paused = true
+				}
+			}
+			if err == nil {
+				err = p.render()
+			}
+		}
+	}
+	return err
+}
+
+// openInput opens the input stream. Standard input is read in place if it is redirected from
+// a file, so that it can be seeked, or else as a pipe.
+func (o *playOptions) openInput() (*y4m.Stream, error) {
+	if o.inFile != "-" {
+		return y4m.Open(o.inFile)
+	}
+	fi, err := os.Stdin.Stat()
+	if err == nil && fi.Mode().IsRegular() {
+		return y4m.Open("/dev/stdin")
+	}
+	return y4m.OpenReader(os.Stdin)
+}
+
+// start indexes the stream so that it can be seeked, or if it cannot seek, as when it is read
+// from a pipe, reads its first frame, which is then played in order.
+func (p *player) start() error {
+	idx, err := p.s.BuildIndex()
+	if errors.Is(err, y4m.ErrNotSeekable) {
+		p.frame, err = p.s.ParseFrame()
+		if err == io.EOF {
+			return fmt.Errorf("stream has no frames")
+		}
+		return err
+	} else if err != nil {
+		return err
+	}
+	if len(idx) == 0 {
+		return fmt.Errorf("stream has no frames")
+	}
+	p.idx = idx
+	return nil
+}
+
+// next advances to the next frame, and reports whether there was one.
+func (p *player) next() (bool, error) {
+	if p.idx != nil {
+		if p.n == len(p.idx)-1 {
+			return false, nil
+		}
+		p.n++
+		return true, nil
+	}
+	f, err := p.s.ParseFrame()
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	p.frame = f
+	p.n++
+	return true, nil
+}
+
+// key handles key k, seeking by skip frames for the arrow keys. A stream that cannot seek
+// can only be paused and stepped forward.
+func (p *player) key(k string, skip int) error {
+	if p.idx == nil && k != " " && k != "." {
+		return nil
+	}
+	switch k {
+	case " ":
+		p.paused = !p.paused
+	case ".":
+		p.paused = true
+		_, err := p.next()
+		if err != nil {
+			return err
+		}
+	case ",":
+		p.paused = true
+		p.n--
+	case "right":
+		p.n += skip
+	case "left":
+		p.n -= skip
+	case "home":
+		p.n = 0
+	default:
+		return nil
+	}
+	if p.idx == nil {
+		// do nothing
+	} else if p.n < 0 {
+		p.n = 0
+	} else if p.n >= len(p.idx) {
+		p.n = len(p.idx) - 1
+	}
+	return p.render()
+}
+
+// render draws the current frame using half block characters, each showing two pixels
+// stacked vertically, with a status line below.
+func (p *player) render() error {
+	f, err := p.current()
+	if err != nil {
+		return err
+	}
+	w, h := p.displaySize()
+	if f.Chroma != "444" && f.Chroma != "mono" {
+		f, err = f.ConvertChroma("444")
+		if err != nil {
+			return err
+		}
+	}
+	err = f.Resize(w, h, y4m.Bilinear)
+	if err != nil {
+		return err
+	}
+	img, err := f.ImageRange(p.s.ColorRange)
+	if err != nil {
+		return err
+	}
+	rgb := func(x, y int) string {
+		c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+		return fmt.Sprintf("%d;%d;%d", c.R, c.G, c.B)
+	}
+	fmt.Fprint(p.out, "\x1b[H")
+	for y := 0; y < h; y += 2 {
+		for x := 0; x < w; x++ {
+			fmt.Fprintf(p.out, "\x1b[38;2;%sm\x1b[48;2;%sm▀", rgb(x, y), rgb(x, y+1))
+		}
+		fmt.Fprint(p.out, "\x1b[0m\x1b[K\n")
+	}
+	state := "playing"
+	if p.paused {
+		state = "paused"
+	}
+	t, _ := p.s.TimeOfFrame(p.n)
+	count := ""
+	if p.idx != nil {
+		count = fmt.Sprintf("/%d", len(p.idx))
+	}
+	fmt.Fprintf(p.out, "frame %d%s  %v  %s\x1b[K", p.n+1, count, t.Round(time.Millisecond), state)
+	return p.out.Flush()
+}
+
+// current returns the frame to display, which render may modify.
+func (p *player) current() (*y4m.Frame, error) {
+	if p.idx == nil {
+		return p.frame.Copy(), nil
+	}
+	err := p.s.SeekFrame(p.idx, p.n)
+	if err != nil {
+		return nil, err
+	}
+	return p.s.ParseFrame()
+}
+
+// displaySize returns the size in pixels at which frames are displayed: the requested or
+// terminal width, reduced if necessary to fit the terminal height, with the stream's display
+// aspect ratio. The height is even, as each character shows two pixels.
+func (p *player) displaySize() (w, h int) {
+	cols, rows := terminalSize()
+	w = cols
+	if p.o.width > 0 {
+		w = p.o.width
+	}
+	aspect := float64(p.s.Width) / float64(p.s.Height)
+	if r := p.s.SampleAspectRatio.Float64(); r > 0 {
+		aspect *= r
+	}
+	h = int(float64(w)/aspect+1) / 2 * 2
+	if maxH := 2 * (rows - 1); h > maxH {
+		h = maxH
+		w = int(float64(h) * aspect)
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 2 {
+		h = 2
+	}
+	return w, h
+}
+
+// terminalSize returns the number of columns and rows of the terminal, or 80 by 24 if it
+// cannot be determined.
+func terminalSize() (cols, rows int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin, _ = os.Open("/dev/tty")
+	b, err := cmd.Output()
+	if err != nil {
+		return 80, 24
+	}
+	_, err = fmt.Sscanf(string(b), "%d %d", &rows, &cols)
+	if err != nil || rows < 2 || cols < 1 {
+		return 80, 24
+	}
+	return cols, rows
+}
+
+// terminalKeys puts the terminal into unbuffered mode and returns a channel of key presses,
+// with arrow and home keys named "left", "right" and "home", and a function that restores the
+// terminal. Without a terminal, the channel never delivers.
+func terminalKeys() (<-chan string, func()) {
+	keys := make(chan string)
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return keys, func() {}
+	}
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = tty
+		b, err := cmd.Output()
+		return strings.TrimSpace(string(b)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		tty.Close()
+		return keys, func() {}
+	}
+	stty("-icanon", "-echo", "min", "1")
+	go func() {
+		r := bufio.NewReader(tty)
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				close(keys)
+				return
+			}
+			if b != 0x1b {
+				keys <- string(b)
+				continue
+			}
+			// Escape sequences for the arrow and home keys
+			seq := make([]byte, 2)
+			if _, err := r.Read(seq[:1]); err != nil || seq[0] != '[' {
+				continue
+			}
+			if _, err := r.Read(seq[1:]); err != nil {
+				continue
+			}
+			switch seq[1] {
+			case 'C':
+				keys <- "right"
+			case 'D':
+				keys <- "left"
+			case 'H':
+				keys <- "home"
+			}
+		}
+	}()
+	return keys, func() {
+		stty(saved)
+	}
+}
@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "quality", Summary: "report PSNR and SSIM of a stream against a reference", Run: runQuality})
+}
+
+type qualityOptions struct {
+	format   string
+	vmaf     bool
+	vmafOpts y4m.VMAFOptions
+	noRef    bool
+}
+
+// frameQuality holds the metrics of one frame, numbered from 1.
+type frameQuality struct {
+	Frame int      `json:"frame"`
+	PSNR  metrics  `json:"psnr"`
+	SSIM  metrics  `json:"ssim"`
+	VMAF  *float64 `json:"vmaf,omitempty"`
+}
+
+// qualitySummary holds the minimum, mean and maximum of a metric over all frames.
+type qualitySummary struct {
+	Min  metrics `json:"min"`
+	Mean metrics `json:"mean"`
+	Max  metrics `json:"max"`
+}
+
+// metrics is a y4m.Metrics that can be encoded as JSON.
+type metrics y4m.Metrics
+
+// MarshalJSON encodes the metrics with lower case keys. JSON has no infinity, so the infinite
+// PSNR of identical planes is encoded as null.
+func (m metrics) MarshalJSON() ([]byte, error) {
+	v := func(f float64) *float64 {
+		if math.IsInf(f, 0) {
+			return nil
+		}
+		return &f
+	}
+	return json.Marshal(struct {
+		Y   *float64 `json:"y"`
+		Cb  *float64 `json:"cb"`
+		Cr  *float64 `json:"cr"`
+		All *float64 `json:"all"`
+	}{v(m.Y), v(m.Cb), v(m.Cr), v(m.All)})
+}
+
+func runQuality(fs *flag.FlagSet, args []string) error {
+	o := new(qualityOptions)
+	fs.StringVar(&o.format, "f", "csv", "output format {\"csv\", \"json\"}")
+	fs.BoolVar(&o.noRef, "noref", false, "measure blockiness and blur of a single stream without a reference")
+	fs.BoolVar(&o.vmaf, "vmaf", false, "also measure VMAF by running the vmaf tool")
+	fs.StringVar(&o.vmafOpts.Binary, "vmafbin", "vmaf", "path of the vmaf tool")
+	fs.StringVar(&o.vmafOpts.Model, "vmafmodel", "", "VMAF model version; empty for the tool's default")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [options] reference.y4m distorted.y4m\n", fs.Name())
+		fmt.Fprintf(fs.Output(), "       %s -noref [options] distorted.y4m\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if (o.noRef && fs.NArg() != 1) || (!o.noRef && fs.NArg() != 2) {
+		fs.Usage()
+		return errUsage
+	}
+	if o.format != "csv" && o.format != "json" {
+		return fmt.Errorf("unrecognized output format %q", o.format)
+	}
+	if o.noRef {
+		return o.noReference(fs.Arg(0))
+	}
+	ref, err := y4m.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer ref.Close()
+	dist, err := y4m.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer dist.Close()
+	frames, err := measure(ref, dist)
+	if err != nil {
+		return err
+	}
+	var vmaf *y4m.Scores
+	if o.vmaf {
+		vmaf, err = y4m.RunVMAF(fs.Arg(0), fs.Arg(1), o.vmafOpts)
+		if err != nil {
+			return err
+		}
+		if len(vmaf.Frames) != len(frames) {
+			return fmt.Errorf("vmaf scored %d frames of %d", len(vmaf.Frames), len(frames))
+		}
+		for k := range frames {
+			frames[k].VMAF = &vmaf.Frames[k]
+		}
+	}
+	if o.format == "json" {
+		return writeQualityJSON(os.Stdout, frames, vmaf)
+	}
+	return writeQualityCSV(os.Stdout, frames, vmaf)
+}
+
+// measure computes the metrics of every frame of dist against the corresponding frame of ref.
+func measure(ref, dist *y4m.Stream) ([]frameQuality, error) {
+	var frames []frameQuality
+	for n := 1; ; n++ {
+		fr, errR := ref.ParseFrame()
+		fd, errD := dist.ParseFrame()
+		if errR == io.EOF && errD == io.EOF {
+			return frames, nil
+		} else if errR == io.EOF || errD == io.EOF {
+			return nil, fmt.Errorf("frame counts differ: one stream ends before frame %d", n)
+		} else if errR != nil {
+			return nil, errR
+		} else if errD != nil {
+			return nil, errD
+		}
+		q := frameQuality{Frame: n}
+		p, err := y4m.PSNR(fr, fd)
+		if err != nil {
+			return nil, err
+		}
+		s, err := y4m.SSIM(fr, fd)
+		if err != nil {
+			return nil, err
+		}
+		q.PSNR, q.SSIM = metrics(p), metrics(s)
+		frames = append(frames, q)
+	}
+}
+
+// summarize returns the summary statistics of the metric selected by m over frames.
+func summarize(frames []frameQuality, m func(frameQuality) metrics) qualitySummary {
+	var s qualitySummary
+	if len(frames) == 0 {
+		return s
+	}
+	s.Min, s.Max = m(frames[0]), m(frames[0])
+	for _, f := range frames {
+		v := m(f)
+		for _, p := range []struct{ v, min, max, sum *float64 }{
+			{&v.Y, &s.Min.Y, &s.Max.Y, &s.Mean.Y},
+			{&v.Cb, &s.Min.Cb, &s.Max.Cb, &s.Mean.Cb},
+			{&v.Cr, &s.Min.Cr, &s.Max.Cr, &s.Mean.Cr},
+			{&v.All, &s.Min.All, &s.Max.All, &s.Mean.All},
+		} {
+			*p.min = math.Min(*p.min, *p.v)
+			*p.max = math.Max(*p.max, *p.v)
+			*p.sum += *p.v
+		}
+	}
+	n := float64(len(frames))
+	s.Mean = metrics{Y: s.Mean.Y / n, Cb: s.Mean.Cb / n, Cr: s.Mean.Cr / n, All: s.Mean.All / n}
+	return s
+}
+
+func psnrOf(f frameQuality) metrics { return f.PSNR }
+
+func ssimOf(f frameQuality) metrics { return f.SSIM }
+
+// formatMetric formats metric value v, writing infinite PSNR as "inf".
+func formatMetric(v float64) string {
+	if math.IsInf(v, 1) {
+		return "inf"
+	}
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+// writeQualityCSV writes the metrics of frames as CSV, followed by their summary. A VMAF
+// column is added if vmaf holds VMAF scores.
+func writeQualityCSV(w io.Writer, frames []frameQuality, vmaf *y4m.Scores) error {
+	row := func(label string, p, s metrics, v float64) error {
+		line := fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%s,%s", label,
+			formatMetric(p.Y), formatMetric(p.Cb), formatMetric(p.Cr), formatMetric(p.All),
+			formatMetric(s.Y), formatMetric(s.Cb), formatMetric(s.Cr), formatMetric(s.All))
+		if vmaf != nil {
+			line += "," + formatMetric(v)
+		}
+		_, err := fmt.Fprintln(w, line)
+		return err
+	}
+	header := "frame,psnr_y,psnr_cb,psnr_cr,psnr_all,ssim_y,ssim_cb,ssim_cr,ssim_all"
+	v := new(y4m.Scores)
+	if vmaf != nil {
+		header += ",vmaf"
+		v = vmaf
+	}
+	_, err := fmt.Fprintln(w, header)
+	if err != nil {
+		return err
+	}
+	for _, f := range frames {
+		var score float64
+		if f.VMAF != nil {
+			score = *f.VMAF
+		}
+		err = row(strconv.Itoa(f.Frame), f.PSNR, f.SSIM, score)
+		if err != nil {
+			return err
+		}
+	}
+	p, s := summarize(frames, psnrOf), summarize(frames, ssimOf)
+	for _, r := range []struct {
+		label string
+		p, s  metrics
+		v     float64
+	}{{"min", p.Min, s.Min, v.Min}, {"mean", p.Mean, s.Mean, v.Mean}, {"max", p.Max, s.Max, v.Max}} {
+		err = row(r.label, r.p, r.s, r.v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeQualityJSON writes the metrics of frames and their summary as JSON, including VMAF if
+// vmaf holds VMAF scores.
+func writeQualityJSON(w io.Writer, frames []frameQuality, vmaf *y4m.Scores) error {
+	summary := map[string]interface{}{
+		"psnr": summarize(frames, psnrOf),
+		"ssim": summarize(frames, ssimOf),
+	}
+	if vmaf != nil {
+		summary["vmaf"] = struct {
+			Min          float64 `json:"min"`
+			Mean         float64 `json:"mean"`
+			HarmonicMean float64 `json:"harmonicMean"`
+			Max          float64 `json:"max"`
+		}{vmaf.Min, vmaf.Mean, vmaf.HarmonicMean, vmaf.Max}
+	}
+	out := struct {
+		Frames  []frameQuality         `json:"frames"`
+		Summary map[string]interface{} `json:"summary"`
+	}{frames, summary}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(out)
+}
+
+// noRefMetrics holds the no-reference metrics of a frame.
+type noRefMetrics struct {
+	Blockiness float64 `json:"blockiness"`
+	EdgeWidth  float64 `json:"edgeWidth"`
+}
+
+// frameNoRef holds the no-reference metrics of one frame, numbered from 1.
+type frameNoRef struct {
+	Frame int `json:"frame"`
+	noRefMetrics
+}
+
+// noReference writes the no-reference metrics of every frame of the named stream, followed by
+// their minimum, mean and maximum.
+func (o *qualityOptions) noReference(name string) error {
+	s, err := y4m.Open(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	var frames []frameNoRef
+	for n := 1; ; n++ {
+		f, err := s.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		frames = append(frames, frameNoRef{n, noRefMetrics{y4m.Blockiness(f), y4m.EdgeWidth(f)}})
+	}
+	var min, mean, max noRefMetrics
+	for k, f := range frames {
+		if k == 0 {
+			min, max = f.noRefMetrics, f.noRefMetrics
+		}
+		min.Blockiness, max.Blockiness = math.Min(min.Blockiness, f.Blockiness), math.Max(max.Blockiness, f.Blockiness)
+		min.EdgeWidth, max.EdgeWidth = math.Min(min.EdgeWidth, f.EdgeWidth), math.Max(max.EdgeWidth, f.EdgeWidth)
+		mean.Blockiness += f.Blockiness / float64(len(frames))
+		mean.EdgeWidth += f.EdgeWidth / float64(len(frames))
+	}
+	if o.format == "json" {
+		out := struct {
+			Frames  []frameNoRef            `json:"frames"`
+			Summary map[string]noRefMetrics `json:"summary"`
+		}{frames, map[string]noRefMetrics{"min": min, "mean": mean, "max": max}}
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(out)
+	}
+	fmt.Println("frame,blockiness,edge_width")
+	for _, f := range frames {
+		fmt.Printf("%d,%s,%s\n", f.Frame, formatMetric(f.Blockiness), formatMetric(f.EdgeWidth))
+	}
+	for _, r := range []struct {
+		label string
+		f     noRefMetrics
+	}{{"min", min}, {"mean", mean}, {"max", max}} {
+		fmt.Printf("%s,%s,%s\n", r.label, formatMetric(r.f.Blockiness), formatMetric(r.f.EdgeWidth))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "raw", Summary: "convert between raw planar YUV and y4m", Run: runRaw})
+}
+
+// pixelFormats maps the pixel format names used by FFmpeg to y4m chroma formats.
+var pixelFormats = map[string]string{
+	"yuv420p":  "420jpeg",
+	"yuv422p":  "422",
+	"yuv444p":  "444",
+	"yuva444p": "444alpha",
+	"yuv411p":  "411",
+	"gray":     "mono",
+	"yuyv422":  "422",
+	"uyvy422":  "422",
+}
+
+// packedFormats maps the names of packed pixel formats to their layouts.
+var packedFormats = map[string]y4m.RawLayout{
+	"yuyv422": y4m.YUY2,
+	"uyvy422": y4m.UYVY,
+}
+
+type rawOptions struct {
+	inFile      string
+	outFile     string
+	width       int
+	height      int
+	pixelFormat string
+	rate        string
+}
+
+func runRaw(fs *flag.FlagSet, args []string) error {
+	o := new(rawOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file, raw YUV or y4m")
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.IntVar(&o.width, "w", 0, "(raw input only) width")
+	fs.IntVar(&o.height, "h", 0, "(raw input only) height")
+	fs.StringVar(&o.pixelFormat, "pix_fmt", "yuv420p",
+		"(raw input only) pixel format {yuv420p, yuv422p, yuv444p, yuva444p, yuv411p, gray, yuyv422, uyvy422}")
+	fs.StringVar(&o.rate, "fps", "25:1", "(raw input only) frame rate N:D, decimal or name such as ntsc")
+	err := parse(fs, args, &o.inFile, &o.outFile)
+	if err != nil {
+		return err
+	}
+	isY4M, err := hasStreamSignature(o.inFile)
+	if err != nil {
+		return err
+	}
+	if isY4M {
+		return o.toRaw()
+	}
+	return o.fromRaw()
+}
+
+// hasStreamSignature reports whether the named file begins with the y4m signature.
+func hasStreamSignature(name string) (bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	b := make([]byte, len("YUV4MPEG2 "))
+	_, err = io.ReadFull(f, b)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return string(b) == "YUV4MPEG2 ", nil
+}
+
+// toRaw writes the planes of every frame of the y4m input, without headers.
+func (o *rawOptions) toRaw() error {
+	s, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	f, err := os.Create(o.outFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for {
+		frame, err := s.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		for _, p := range [][]byte{frame.Y, frame.Cb, frame.Cr, frame.Alpha} {
+			_, err = w.Write(p)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// fromRaw writes the raw planar or packed input as a y4m stream with the given geometry and
+// frame rate.
+func (o *rawOptions) fromRaw() error {
+	chroma, ok := pixelFormats[o.pixelFormat]
+	if !ok {
+		return fmt.Errorf("unrecognized pixel format %q", o.pixelFormat)
+	}
+	if o.width <= 0 || o.height <= 0 {
+		return fmt.Errorf("width and height are required for raw input")
+	}
+	rate, err := parseRate(o.rate)
+	if err != nil {
+		return err
+	}
+	frame, err := y4m.NewFrame(o.width, o.height, chroma)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	r := bufio.NewReader(in)
+	s, err := y4m.NewStream(o.outFile, o.width, o.height)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	err = s.SetChroma(chroma)
+	if err != nil {
+		return err
+	}
+	s.FrameRate = rate
+	s.Interlacing = "p"
+	s.SampleAspectRatio = &y4m.Ratio{N: 1, D: 1}
+	err = s.WriteHeader()
+	if err != nil {
+		return err
+	}
+	layout, packed := packedFormats[o.pixelFormat]
+	var buf []byte
+	if packed {
+		size, err := y4m.RawSize(o.width, o.height, layout)
+		if err != nil {
+			return err
+		}
+		buf = make([]byte, size)
+	}
+	for k := 1; ; k++ {
+		planes := [][]byte{frame.Y, frame.Cb, frame.Cr, frame.Alpha}
+		if packed {
+			planes = [][]byte{buf}
+		}
+		for i, p := range planes {
+			_, err = io.ReadFull(r, p)
+			if err == io.EOF && i == 0 {
+				return s.Sync()
+			} else if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("frame %d is truncated; check the size and pixel format", k)
+			} else if err != nil {
+				return err
+			}
+		}
+		if packed {
+			frame, err = y4m.FrameFromRaw(buf, o.width, o.height, layout)
+			if err != nil {
+				return err
+			}
+		}
+		err = s.WriteFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "scale", Summary: "resize a stream", Run: runScale})
+}
+
+type scaleOptions struct {
+	inFile  string
+	outFile string
+	width   int
+	height  int
+	kernel  string
+	square  bool
+}
+
+func runScale(fs *flag.FlagSet, args []string) error {
+	o := new(scaleOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.IntVar(&o.width, "w", -1, "output width; -1 to keep the display aspect ratio")
+	fs.IntVar(&o.height, "h", -1, "output height; -1 to keep the display aspect ratio")
+	fs.StringVar(&o.kernel, "k", "bicubic", "kernel {nearest, bilinear, bicubic, lanczos}")
+	fs.BoolVar(&o.square, "square", false, "resample to square pixels using the sample aspect ratio; overrides -w and -h")
+	err := parse(fs, args, &o.inFile, &o.outFile)
+	if err != nil {
+		return err
+	}
+	return o.scale()
+}
+
+func (o *scaleOptions) scale() error {
+	k, err := y4m.ParseKernel(o.kernel)
+	if err != nil {
+		return err
+	}
+	sIn, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	defer sIn.Close()
+	if o.square {
+		sOut, err := createLike(o.outFile, sIn)
+		if err != nil {
+			return err
+		}
+		defer sOut.Close()
+		err = y4m.ResampleToSquarePixels(sOut, sIn, k)
+		if err != nil {
+			return err
+		}
+		return sOut.Sync()
+	}
+	err = o.setSize(sIn)
+	if err != nil {
+		return err
+	}
+	sOut, err := createLike(o.outFile, sIn)
+	if err != nil {
+		return err
+	}
+	defer sOut.Close()
+	sOut.Resize(o.width, o.height)
+	err = sOut.WriteHeader()
+	if err != nil {
+		return err
+	}
+	for {
+		frame, err := sIn.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		err = frame.Resize(o.width, o.height, k)
+		if err != nil {
+			return err
+		}
+		err = sOut.WriteFrame(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return sOut.Sync()
+}
+
+// setSize derives an unspecified output dimension from the other so that the display aspect
+// ratio of stream s is kept, rounding to a multiple of the chroma subsampling.
+func (o *scaleOptions) setSize(s *y4m.Stream) error {
+	xss, yss := s.XSubsamplingFactor, s.YSubsamplingFactor
+	if s.Chroma == "mono" {
+		xss, yss = 1, 1
+	}
+	round := func(v, m int) int {
+		v = (v + m/2) / m * m
+		if v < m {
+			return m
+		}
+		return v
+	}
+	switch {
+	case o.width == -1 && o.height == -1:
+		return fmt.Errorf("specify the output width, height or both")
+	case o.width == -1:
+		o.width = round(s.Width*o.height/s.Height, xss)
+	case o.height == -1:
+		o.height = round(s.Height*o.width/s.Width, yss)
+	}
+	if o.width < 1 || o.height < 1 {
+		return fmt.Errorf("output size %dx%d must be positive", o.width, o.height)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "serve", Summary: "serve a stream over HTTP", Run: runServe})
+}
+
+type serveOptions struct {
+	inFile string
+	addr   string
+	path   string
+}
+
+func runServe(fs *flag.FlagSet, args []string) error {
+	o := new(serveOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file")
+	fs.StringVar(&o.addr, "addr", "localhost:8080", "address to listen on, host:port")
+	fs.StringVar(&o.path, "path", "/", "URL path at which the stream is served")
+	err := parse(fs, args, &o.inFile)
+	if err != nil {
+		return err
+	}
+	return o.serve()
+}
+
+// serve serves the input stream until the process is stopped. The stream file is opened
+// afresh for each request, so each client receives the whole stream.
+func (o *serveOptions) serve() error {
+	// Check the stream now rather than on the first request
+	s, err := y4m.Open(o.inFile)
+	if err != nil {
+		return err
+	}
+	s.Close()
+	mux := http.NewServeMux()
+	mux.Handle(o.path, y4m.FileHandler(o.inFile))
+	ln, err := net.Listen("tcp", o.addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "serving %s at http://%s%s\n", o.inFile, ln.Addr(), o.path)
+	return http.Serve(ln, mux)
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "stack", Summary: "stack streams side by side for comparison", Run: runStack})
+}
+
+type stackOptions struct {
+	outFile  string
+	vertical bool
+	kernel   string
+	anyRate  bool
+}
+
+func runStack(fs *flag.FlagSet, args []string) error {
+	o := new(stackOptions)
+	fs.StringVar(&o.outFile, "o", "", "output file")
+	fs.BoolVar(&o.vertical, "v", false, "stack top to bottom instead of left to right")
+	fs.StringVar(&o.kernel, "k", "bicubic", "kernel for scaling {nearest, bilinear, bicubic, lanczos}")
+	fs.BoolVar(&o.anyRate, "anyrate", false, "accept inputs whose frame rate differs from the first")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s -o output [options] input input...\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	err := parse(fs, args, &o.outFile)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return errUsage
+	}
+	return o.stack(fs.Args())
+}
+
+func (o *stackOptions) stack(names []string) error {
+	k, err := y4m.ParseKernel(o.kernel)
+	if err != nil {
+		return err
+	}
+	var ins []*y4m.Stream
+	for _, name := range names {
+		s, err := y4m.Open(name)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		ins = append(ins, s)
+	}
+	first := ins[0]
+	// Size of each input in the output, keeping its display aspect ratio
+	sizes := make([][2]int, len(ins))
+	w, h := 0, 0
+	for i, s := range ins {
+		r0, r := first.FrameRate, s.FrameRate
+		if !o.anyRate && r0.Cmp(r) != 0 {
+			return fmt.Errorf("%s: frame rate %v does not match %v", names[i], r, r0)
+		}
+		sizes[i] = o.size(first, s)
+		if o.vertical {
+			w, h = sizes[i][0], h+sizes[i][1]
+		} else {
+			w, h = w+sizes[i][0], sizes[i][1]
+		}
+	}
+	out, err := createLike(o.outFile, first)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	out.Width, out.Height = w, h
+	for _, s := range ins[1:] {
+		if s.Interlacing != first.Interlacing {
+			out.Interlacing = "?"
+		}
+	}
+	err = out.WriteHeader()
+	if err != nil {
+		return err
+	}
+	// The output ends with the shortest input
+	for {
+		frames := make([]*y4m.Frame, len(ins))
+		for i, s := range ins {
+			f, err := s.ParseFrame()
+			if err == io.EOF {
+				return out.Sync()
+			} else if err != nil {
+				return err
+			}
+			if f.Chroma != first.Chroma {
+				f, err = f.ConvertChroma(first.Chroma)
+				if err != nil {
+					return err
+				}
+			}
+			if f.Width != sizes[i][0] || f.Height != sizes[i][1] {
+				err = f.Resize(sizes[i][0], sizes[i][1], k)
+				if err != nil {
+					return err
+				}
+			}
+			frames[i] = f
+		}
+		f, err := y4m.Stack(o.vertical, frames...)
+		if err != nil {
+			return err
+		}
+		err = out.WriteFrame(f)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// size returns the size of stream s scaled to the height of stream first, or its width if
+// stacking vertically, keeping the display aspect ratio of s given the sample aspect ratio of
+// first. The scaled dimension is rounded to a multiple of the chroma subsampling.
+func (o *stackOptions) size(first, s *y4m.Stream) [2]int {
+	if s == first {
+		return [2]int{s.Width, s.Height}
+	}
+	sar := func(s *y4m.Stream) float64 {
+		if r := s.SampleAspectRatio; r != nil && r.N > 0 && r.D > 0 {
+			return float64(r.N) / float64(r.D)
+		}
+		return 1
+	}
+	xss, yss := first.XSubsamplingFactor, first.YSubsamplingFactor
+	if first.Chroma == "mono" {
+		xss, yss = 1, 1
+	}
+	round := func(v float64, m int) int {
+		n := int(math.Round(v/float64(m))) * m
+		if n < m {
+			return m
+		}
+		return n
+	}
+	dar := float64(s.Width) * sar(s) / float64(s.Height)
+	if o.vertical {
+		return [2]int{first.Width, round(float64(first.Width)*sar(first)/dar, yss)}
+	}
+	return [2]int{round(float64(first.Height)*dar/sar(first), xss), first.Height}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/egtork/y4mlib"
+)
+
+func init() {
+	register(&Command{Name: "validate", Summary: "check a stream for conformance", Run: runValidate})
+}
+
+type validateOptions struct {
+	inFile string
+	format string
+}
+
+var errInvalid = errors.New("stream is not valid")
+
+// problem is a y4m.Problem that can be encoded as JSON.
+type problem struct {
+	Offset   int64  `json:"offset"`
+	Frame    *int   `json:"frame"` // nil for the stream header
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// validationReport is a y4m.ValidationReport that can be encoded as JSON.
+type validationReport struct {
+	File     string    `json:"file"`
+	Valid    bool      `json:"valid"`
+	Frames   int       `json:"frames"`
+	Size     int64     `json:"size"`
+	Problems []problem `json:"problems"`
+}
+
+func runValidate(fs *flag.FlagSet, args []string) error {
+	o := new(validateOptions)
+	fs.StringVar(&o.inFile, "i", "", "input file; - for standard input")
+	fs.StringVar(&o.format, "f", "text", "output format {\"text\", \"json\"}")
+	err := parse(fs, args, &o.inFile)
+	if err != nil {
+		return err
+	}
+	if o.format != "text" && o.format != "json" {
+		return fmt.Errorf("unrecognized output format %q", o.format)
+	}
+	return o.validate()
+}
+
+func (o *validateOptions) validate() error {
+	f := os.Stdin
+	if o.inFile != "-" {
+		var err error
+		f, err = os.Open(o.inFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	r, err := y4m.Validate(f)
+	if err != nil {
+		return err
+	}
+	if o.format == "json" {
+		err = o.printJSON(r)
+	} else {
+		for _, p := range r.Problems {
+			fmt.Println(p)
+		}
+		fmt.Printf("%d frames, %d octets, %d problems\n", r.Frames, r.Size, len(r.Problems))
+	}
+	if err != nil {
+		return err
+	}
+	if !r.Valid() {
+		return errInvalid
+	}
+	return nil
+}
+
+func (o *validateOptions) printJSON(r *y4m.ValidationReport) error {
+	v := validationReport{File: o.inFile, Valid: r.Valid(), Frames: r.Frames, Size: r.Size,
+		Problems: []problem{}}
+	for _, p := range r.Problems {
+		q := problem{Offset: p.Offset, Severity: "error", Message: p.Message}
+		if p.Frame >= 0 {
+			n := p.Frame
+			q.Frame = &n
+		}
+		if p.Warning {
+			q.Severity = "warning"
+		}
+		v.Problems = append(v.Problems, q)
+	}
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	return e.Encode(v)
+}
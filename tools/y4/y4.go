@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/egtork/y4mlib/tools/internal/cli"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "-help" {
+		usage()
+		return
+	}
+	name := os.Args[1]
+	os.Exit(cli.Run("y4 "+name, name, os.Args[2:]))
+}
+
+func usage() {
+	fmt.Println("usage: y4 command [options]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, c := range cli.Commands() {
+		fmt.Printf("  %-10s %s\n", c.Name, c.Summary)
+	}
+	fmt.Println()
+	fmt.Println("Run \"y4 command -h\" for the options of a command.")
+}
@@ -0,0 +1,9 @@
+package main
+
+import (
+	"github.com/egtork/y4mlib/tools/internal/cli"
+)
+
+func main() {
+	cli.Main("y4cat", "cat")
+}
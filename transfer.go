@@ -0,0 +1,179 @@
+package y4m
+
+import (
+	"fmt"
+	"math"
+)
+
+// TransferFunction relates nonlinear R'G'B' signal values to linear light.
+type TransferFunction int
+
+// Transfer functions. Linear values are relative, with 1 the nominal peak: for PQ, 1 is the
+// absolute luminance of 10000 cd/m², and for HLG, linear values are scene light without the
+// display's system gamma applied.
+const (
+	TransferBT709 TransferFunction = iota // BT.709, also used by BT.601 and SDR BT.2020
+	TransferSRGB                          // IEC 61966-2-1
+	TransferPQ                            // SMPTE ST 2084 perceptual quantizer, used by HDR10
+	TransferHLG                           // BT.2100 hybrid log-gamma
+)
+
+func (t TransferFunction) String() string {
+	switch t {
+	case TransferBT709:
+		return "BT.709"
+	case TransferSRGB:
+		return "sRGB"
+	case TransferPQ:
+		return "PQ"
+	case TransferHLG:
+		return "HLG"
+	}
+	return fmt.Sprintf("TransferFunction(%d)", int(t))
+}
+
+// Constants of the PQ and HLG transfer functions
+const (
+	pqM1 = 2610.0 / 16384
+	pqM2 = 2523.0 / 4096 * 128
+	pqC1 = 3424.0 / 4096
+	pqC2 = 2413.0 / 4096 * 32
+	pqC3 = 2392.0 / 4096 * 32
+
+	hlgA = 0.17883277
+	hlgB = 1 - 4*hlgA
+	hlgC = 0.55991073
+)
+
+// ToLinear converts signal value v in [0, 1] to linear light. Values outside [0, 1] are
+// clamped.
+func (t TransferFunction) ToLinear(v float64) float64 {
+	v = math.Max(0, math.Min(1, v))
+	switch t {
+	case TransferBT709:
+		if v < 0.081 {
+			return v / 4.5
+		}
+		return math.Pow((v+0.099)/1.099, 1/0.45)
+	case TransferSRGB:
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	case TransferPQ:
+		p := math.Pow(v, 1/pqM2)
+		return math.Pow(math.Max(p-pqC1, 0)/(pqC2-pqC3*p), 1/pqM1)
+	case TransferHLG:
+		if v <= 0.5 {
+			return v * v / 3
+		}
+		return (math.Exp((v-hlgC)/hlgA) + hlgB) / 12
+	}
+	return v
+}
+
+// FromLinear converts linear light l in [0, 1] to a signal value, the inverse of ToLinear.
+// Values outside [0, 1] are clamped.
+func (t TransferFunction) FromLinear(l float64) float64 {
+	l = math.Max(0, math.Min(1, l))
+	switch t {
+	case TransferBT709:
+		if l < 0.018 {
+			return 4.5 * l
+		}
+		return 1.099*math.Pow(l, 0.45) - 0.099
+	case TransferSRGB:
+		if l <= 0.0031308 {
+			return 12.92 * l
+		}
+		return 1.055*math.Pow(l, 1/2.4) - 0.055
+	case TransferPQ:
+		p := math.Pow(l, pqM1)
+		return math.Pow((pqC1+pqC2*p)/(1+pqC3*p), pqM2)
+	case TransferHLG:
+		if l <= 1.0/12 {
+			return math.Sqrt(3 * l)
+		}
+		return hlgA*math.Log(12*l-hlgB) + hlgC
+	}
+	return l
+}
+
+// LinearImage holds the linear-light red, green and blue planes of a frame, and its alpha plane
+// if it has one, as float32 samples indexed by y*Width+x. Linear values can be scaled, blended
+// and averaged correctly, unlike the gamma-corrected samples of a frame.
+type LinearImage struct {
+	Width  int
+	Height int
+	R      []float32
+	G      []float32
+	B      []float32
+	A      []float32 // alpha in [0, 1], or nil
+}
+
+// ToLinear converts the frame to linear light. The samples, in range r, are converted to R'G'B'
+// with matrix m as by ToNRGBA, without rounding to 8 bits, and then linearized with transfer
+// function t.
+func (f *Frame) ToLinear(m Matrix, r ColorRange, t TransferFunction) (*LinearImage, error) {
+	n := f.Width * f.Height
+	img := &LinearImage{Width: f.Width, Height: f.Height,
+		R: make([]float32, n), G: make([]float32, n), B: make([]float32, n)}
+	err := f.eachRGB(m, r, func(k int, red, green, blue float64) {
+		img.R[k] = float32(t.ToLinear(red))
+		img.G[k] = float32(t.ToLinear(green))
+		img.B[k] = float32(t.ToLinear(blue))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Alpha) > 0 {
+		img.A = make([]float32, n)
+		for k, a := range f.Alpha {
+			img.A[k] = float32(a) / 255
+		}
+	}
+	return img, nil
+}
+
+// FrameFromLinear converts linear-light image img to a frame in the given chroma format, the
+// inverse of ToLinear: the planes are encoded with transfer function t, converted to Y'CbCr
+// with matrix m, and quantized to range r. Chroma is downsampled by averaging as by
+// FrameFromImage. Alpha is taken from the image when converting to 444alpha, and is opaque if
+// the image has none.
+func FrameFromLinear(img *LinearImage, chroma string, m Matrix, r ColorRange, t TransferFunction) (*Frame, error) {
+	kr, kb, err := m.coefficients()
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewFrame(img.Width, img.Height, chroma)
+	if err != nil {
+		return nil, err
+	}
+	yOffset, yScale, cScale := 0.0, 255.0, 255.0
+	if r == ColorRangeLimited {
+		yOffset, yScale, cScale = 16, 219, 224
+	}
+	n := img.Width * img.Height
+	cb, cr := make([]byte, n), make([]byte, n)
+	for k := 0; k < n; k++ {
+		red := t.FromLinear(float64(img.R[k]))
+		green := t.FromLinear(float64(img.G[k]))
+		blue := t.FromLinear(float64(img.B[k]))
+		y := kr*red + (1-kr-kb)*green + kb*blue
+		f.Y[k] = clampByte(int(math.Round(yOffset + yScale*y)))
+		cb[k] = clampByte(int(math.Round(128 + cScale*(blue-y)/(2*(1-kb)))))
+		cr[k] = clampByte(int(math.Round(128 + cScale*(red-y)/(2*(1-kr)))))
+	}
+	if len(f.Cb) > 0 {
+		xss, yss := xSubsamplingFactor[chroma], ySubsamplingFactor[chroma]
+		downsample(f.Cb, cb, img.Width, img.Height, xss, yss)
+		downsample(f.Cr, cr, img.Width, img.Height, xss, yss)
+	}
+	for k := range f.Alpha {
+		f.Alpha[k] = 0xff
+		if img.A != nil {
+			f.Alpha[k] = clampByte(int(math.Round(float64(img.A[k]) * 255)))
+		}
+	}
+	return f, nil
+}
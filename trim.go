@@ -0,0 +1,66 @@
+package y4m
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Trim copies the frames of the stream numbered start up to but not including end, counting
+// from zero, to stream out. An end of -1 copies through the last frame. The frames are
+// copied as a single byte range, without being parsed, so out must have the same geometry and
+// chroma format as the stream, and its header should be written beforehand. If the stream
+// ends before frame end, the frames that remain are copied and an error is returned. The
+// stream is left positioned after the last frame copied.
+func (s *Stream) Trim(out *Stream, start, end int) error {
+	if out.Width != s.Width || out.Height != s.Height || out.Chroma != s.Chroma {
+		return fmt.Errorf("cannot trim %dx%d %s stream into %dx%d %s stream",
+			s.Width, s.Height, s.Chroma, out.Width, out.Height, out.Chroma)
+	}
+	if start < 0 || (end != -1 && end < start) {
+		return fmt.Errorf("invalid frame range [%d, %d)", start, end)
+	}
+	err := s.ToFirstFrame()
+	if err != nil {
+		return err
+	}
+	for s.frameIndex < start {
+		err = s.SkipFrame()
+		if err == io.EOF {
+			return errFrameOutOfRange(start, s.frameIndex)
+		} else if err != nil {
+			return err
+		}
+	}
+	_, err = s.copyFrames(out.writer(), end)
+	if err == io.EOF {
+		return errFrameOutOfRange(end-1, s.frameIndex)
+	}
+	return err
+}
+
+// TrimTime is like Trim, but selects the frames displayed from time start up to but not
+// including time end, measured from the beginning of the stream using its frame rate. A
+// negative end copies through the last frame.
+func (s *Stream) TrimTime(out *Stream, start, end time.Duration) error {
+	first, err := s.FrameAtTime(start)
+	if err != nil {
+		return err
+	}
+	last := -1
+	if end >= 0 {
+		last, err = s.FrameAtTime(end)
+		if err != nil {
+			return err
+		}
+		// Include the frame displayed at end only if it starts before end
+		t, err := s.TimeOfFrame(last)
+		if err != nil {
+			return err
+		}
+		if t < end {
+			last++
+		}
+	}
+	return s.Trim(out, first, last)
+}
@@ -0,0 +1,330 @@
+package y4m
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Problem is a conformance problem found by Validate.
+type Problem struct {
+	Offset  int64 // byte offset of the problem in the stream
+	Frame   int   // frame number, counting from zero, or -1 for the stream header
+	Warning bool  // the stream can be read, but may be misinterpreted
+	Message string
+}
+
+func (p Problem) String() string {
+	where := "header"
+	if p.Frame >= 0 {
+		where = fmt.Sprintf("frame %d", p.Frame)
+	}
+	kind := "error"
+	if p.Warning {
+		kind = "warning"
+	}
+	return fmt.Sprintf("offset %d, %s: %s: %s", p.Offset, where, kind, p.Message)
+}
+
+// ValidationReport is the result of validating a stream.
+type ValidationReport struct {
+	Frames   int   // number of complete frames
+	Size     int64 // number of octets read
+	Problems []Problem
+}
+
+// Valid reports whether the stream has no problems other than warnings.
+func (r *ValidationReport) Valid() bool {
+	for _, p := range r.Problems {
+		if !p.Warning {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate reads a stream from r and checks its conformance to the YUV4MPEG2 format: the
+// syntax of the stream header and of every frame header, the length of the planar data of
+// each frame, the end of the stream falling on a frame boundary, and the consistency of frame
+// I fields with the stream's interlacing. After a frame that is not followed by a frame header,
+// validation resumes at the next frame header found. The returned error reports a failure to
+// read r, not a problem with the stream.
+func Validate(r io.Reader) (*ValidationReport, error) {
+	v := &validator{r: bufio.NewReader(r), report: new(ValidationReport), frame: -1}
+	s, err := v.header()
+	if err != nil || s == nil {
+		return v.report, err
+	}
+	err = v.frames(s)
+	v.report.Size = v.pos
+	return v.report, err
+}
+
+type validator struct {
+	r      *bufio.Reader
+	pos    int64
+	frame  int // number of the frame being checked, or -1 for the stream header
+	report *ValidationReport
+}
+
+func (v *validator) problem(offset int64, warning bool, format string, a ...interface{}) {
+	v.report.Problems = append(v.report.Problems,
+		Problem{Offset: offset, Frame: v.frame, Warning: warning, Message: fmt.Sprintf(format, a...)})
+}
+
+// line reads a header line including its terminating '\n'. At the end of the stream it
+// returns the partial line and io.EOF. A line longer than DefaultLimits allow is not read
+// further, and the error wraps ErrLimitExceeded. The line is only valid until the next read.
+func (v *validator) line() ([]byte, error) {
+	b, err := readLine(v.r, DefaultLimits.MaxHeaderLength)
+	v.pos += int64(len(b))
+	return b, err
+}
+
+// fields splits header line b, which begins at offset, into fields separated by single
+// spaces, reporting empty fields, and returns the fields with their offsets.
+func (v *validator) fields(b []byte, offset int64) ([]string, []int64) {
+	var fields []string
+	var offsets []int64
+	b = bytes.TrimSuffix(b, []byte{'\n'})
+	pos := offset
+	for _, f := range strings.Split(string(b), " ") {
+		if f == "" {
+			v.problem(pos, false, "empty field; fields must be separated by a single space")
+		} else {
+			fields = append(fields, f)
+			offsets = append(offsets, pos)
+		}
+		pos += int64(len(f)) + 1
+	}
+	return fields, offsets
+}
+
+// header checks the stream header and returns a stream describing it, or nil if the frames
+// cannot be checked.
+func (v *validator) header() (*Stream, error) {
+	b, err := v.line()
+	if err == io.EOF {
+		if len(b) == 0 {
+			v.problem(0, false, "stream is empty")
+		} else {
+			v.problem(0, false, "stream header is not terminated by a newline")
+		}
+		return nil, nil
+	} else if errors.Is(err, ErrLimitExceeded) {
+		v.problem(0, false, "%v; validation stopped", err)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	fields, offsets := v.fields(b, 0)
+	if len(fields) == 0 || fields[0] != streamMagicString {
+		v.problem(0, false, "stream does not begin with the signature %q", streamMagicString)
+		return nil, nil
+	}
+	s := &Stream{Chroma: "420jpeg", Interlacing: "?", FrameRate: &Ratio{0, 0}, SampleAspectRatio: &Ratio{0, 0}}
+	seen := map[byte]bool{}
+	for k := 1; k < len(fields); k++ {
+		key, val, at := fields[k][0], fields[k][1:], offsets[k]
+		if key != 'X' {
+			if seen[key] {
+				v.problem(at, false, "duplicate %c field", key)
+			}
+			seen[key] = true
+		}
+		switch key {
+		case 'W', 'H':
+			var n int
+			_, err := fmt.Sscanf(val, "%d", &n)
+			if err != nil || n <= 0 || fmt.Sprint(n) != val {
+				v.problem(at, false, "invalid %c field %q", key, fields[k])
+			} else if key == 'W' {
+				s.Width = n
+			} else {
+				s.Height = n
+			}
+		case 'C':
+			if _, ok := xSubsamplingFactor[val]; !ok && val != "mono" {
+				v.problem(at, false, "unsupported chroma format %q", val)
+				return nil, nil
+			}
+			s.Chroma = val
+		case 'I':
+			if len(val) != 1 || !strings.Contains("ptbm?", val) {
+				v.problem(at, false, "invalid interlacing %q", val)
+			}
+			s.Interlacing = val
+		case 'F':
+			r, err := stringToRatio(val)
+			if err != nil || r.N < 0 || r.D < 0 || (r.N == 0) != (r.D == 0) {
+				v.problem(at, false, "invalid frame rate %q", val)
+			} else if r.N == 0 {
+				v.problem(at, true, "frame rate is unknown")
+			}
+		case 'A':
+			r, err := stringToRatio(val)
+			if err != nil || r.N < 0 || r.D < 0 || (r.N == 0) != (r.D == 0) {
+				v.problem(at, false, "invalid sample aspect ratio %q", val)
+			}
+		case 'X':
+			if val == "" {
+				v.problem(at, true, "empty X field")
+			}
+		default:
+			v.problem(at, false, "unrecognized field %q", fields[k])
+		}
+	}
+	if !seen['F'] {
+		v.problem(0, true, "stream header has no frame rate")
+	}
+	if s.Width == 0 || s.Height == 0 {
+		v.problem(0, false, "stream header does not give the frame size")
+		return nil, nil
+	}
+	err = checkSize(s.Width, s.Height)
+	if err != nil {
+		v.problem(0, false, "%v", err)
+		return nil, nil
+	}
+	if checkGeometry(s.Width, s.Height, s.Chroma) != nil {
+		v.problem(0, true, "dimensions %dx%d are not a multiple of %s chroma subsampling",
+			s.Width, s.Height, s.Chroma)
+	}
+	return s, s.SetChroma(s.Chroma)
+}
+
+// frames checks the frames of stream s.
+func (v *validator) frames(s *Stream) error {
+	size := s.FrameImageDataSize()
+	for {
+		v.frame = v.report.Frames
+		ok, err := v.atFrameHeader()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if v.resync() {
+				continue
+			}
+			break
+		}
+		offset := v.pos
+		b, err := v.line()
+		if err == io.EOF {
+			v.problem(offset, false, "frame header is not terminated by a newline")
+			break
+		} else if errors.Is(err, ErrLimitExceeded) {
+			v.problem(offset, false, "%v; validation stopped", err)
+			break
+		} else if err != nil {
+			return err
+		}
+		v.frameHeader(s, b, offset)
+		n, err := io.CopyN(io.Discard, v.r, size)
+		v.pos += n
+		if err == io.EOF {
+			v.problem(offset, false, "%v: %d of %d octets of planar data", ErrTruncatedFrame, n, size)
+			break
+		} else if err != nil {
+			return err
+		}
+		v.report.Frames++
+	}
+	if v.report.Frames == 0 {
+		v.frame = -1
+		v.problem(v.pos, true, "stream has no frames")
+	}
+	return nil
+}
+
+// atFrameHeader reports whether the stream continues with a frame header. At the end of the
+// stream it returns false without reporting a problem.
+func (v *validator) atFrameHeader() (bool, error) {
+	b, err := v.r.Peek(len("FRAME "))
+	if len(b) == 0 && err == io.EOF {
+		return false, nil
+	} else if err != nil && err != io.EOF {
+		return false, err
+	}
+	if !isFrameStart(b) {
+		v.problem(v.pos, false, "expected frame header; previous frame may have the wrong size")
+		return false, nil
+	}
+	return true, nil
+}
+
+// resync skips to the next frame header, reporting the octets skipped, and reports whether one
+// was found.
+func (v *validator) resync() bool {
+	start := v.pos
+	for {
+		b, _ := v.r.Peek(len("FRAME "))
+		if len(b) == 0 {
+			if v.pos > start {
+				v.problem(start, false, "skipped %d octets to the end of the stream", v.pos-start)
+			}
+			return false
+		}
+		if isFrameStart(b) {
+			v.problem(start, false, "skipped %d octets to the next frame header", v.pos-start)
+			return true
+		}
+		v.r.Discard(1)
+		v.pos++
+	}
+}
+
+// isFrameStart reports whether b begins with a frame header.
+func isFrameStart(b []byte) bool {
+	return len(b) == len("FRAME ") && bytes.HasPrefix(b, []byte("FRAME")) && (b[5] == ' ' || b[5] == '\n')
+}
+
+// frameHeader checks frame header b, which begins at offset, against stream s.
+func (v *validator) frameHeader(s *Stream, b []byte, offset int64) {
+	fields, offsets := v.fields(b, offset)
+	seenI := false
+	for k := 1; k < len(fields); k++ {
+		key, at := fields[k][0], offsets[k]
+		switch key {
+		case 'I':
+			if seenI {
+				v.problem(at, false, "duplicate I field")
+			}
+			seenI = true
+		case 'X':
+			if len(fields[k]) == 1 {
+				v.problem(at, true, "empty X field")
+			}
+		default:
+			v.problem(at, false, "unrecognized field %q", fields[k])
+		}
+	}
+	h, err := parseFrameHeaderBytes(b)
+	if err != nil {
+		v.problem(offset, false, "%v", err)
+		return
+	}
+	if h.I == nil {
+		if s.Interlacing == "m" {
+			v.problem(offset, false, "frame of a mixed-mode (Im) stream has no I field")
+		}
+		return
+	}
+	p := h.I.Presentation
+	fieldOrder := p == PresentTopFirst || p == PresentTopFirstRepeat || p == PresentBottomFirst ||
+		p == PresentBottomFirstRepeat
+	switch {
+	case s.Interlacing == "p" && h.I.Temporal == SamplingInterlaced:
+		v.problem(offset, false, "interlaced temporal sampling %q in a progressive stream", h.I)
+	case s.Interlacing == "t" && (p == PresentBottomFirst || p == PresentBottomFirstRepeat):
+		v.problem(offset, false, "bottom field first presentation %q in a top field first stream", h.I)
+	case s.Interlacing == "b" && (p == PresentTopFirst || p == PresentTopFirstRepeat):
+		v.problem(offset, false, "top field first presentation %q in a bottom field first stream", h.I)
+	case s.Interlacing == "p" && fieldOrder:
+		// Soft telecine of progressive content displays fields in order
+		v.problem(offset, true, "field order presentation %q in a progressive stream", h.I)
+	}
+}
@@ -0,0 +1,226 @@
+package y4m
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// Scores holds a score, such as VMAF, for each frame of a stream and its pooled values over
+// the stream. The harmonic mean weights poor frames more heavily than the mean, and is often
+// reported for VMAF.
+type Scores struct {
+	Frames       []float64
+	Min          float64
+	Max          float64
+	Mean         float64
+	HarmonicMean float64
+}
+
+// newScores returns scores pooling the frame scores s.
+func newScores(s []float64) *Scores {
+	sc := &Scores{Frames: s}
+	if len(s) == 0 {
+		return sc
+	}
+	sc.Min, sc.Max = s[0], s[0]
+	var sum, inv float64
+	for _, v := range s {
+		sc.Min = math.Min(sc.Min, v)
+		sc.Max = math.Max(sc.Max, v)
+		sum += v
+		// As in libvmaf, scores are offset by one so that zero scores are allowed
+		inv += 1 / (v + 1)
+	}
+	n := float64(len(s))
+	sc.Mean = sum / n
+	sc.HarmonicMean = n/inv - 1
+	return sc
+}
+
+// ScoreFrames reads streams ref and dist in step from their current positions and calls score
+// with each pair of corresponding frames, n counting from zero, collecting the scores it
+// returns. It is the hook for metrics computed by other libraries, such as bindings to
+// libvmaf, which receive decoded frame pairs. The streams must have the same number of frames.
+func ScoreFrames(ref, dist *Stream, score func(n int, ref, dist *Frame) (float64, error)) (*Scores, error) {
+	var s []float64
+	for n := 0; ; n++ {
+		fr, errR := ref.ParseFrame()
+		fd, errD := dist.ParseFrame()
+		if errR == io.EOF && errD == io.EOF {
+			return newScores(s), nil
+		} else if errR == io.EOF || errD == io.EOF {
+			return nil, fmt.Errorf("frame counts differ: one stream ends before frame %d", n)
+		} else if errR != nil {
+			return nil, errR
+		} else if errD != nil {
+			return nil, errD
+		}
+		v, err := score(n, fr, fd)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+}
+
+// VMAFOptions configures RunVMAF.
+type VMAFOptions struct {
+	// Binary is the path of the vmaf command line tool from libvmaf. If empty, vmaf is looked
+	// up in the PATH.
+	Binary string
+	// Model is the version of the VMAF model, such as "vmaf_4k_v0.6.1". If empty, the tool's
+	// default model is used.
+	Model string
+	// Threads is the number of threads vmaf may use. Zero leaves the choice to vmaf.
+	Threads int
+}
+
+// vmafChromas lists the chroma formats the vmaf tool reads from y4m files. Streams in other
+// formats are converted to 444.
+var vmafChromas = map[string]bool{"420jpeg": true, "420mpeg2": true, "420paldv": true, "422": true, "444": true}
+
+// RunVMAF measures the VMAF of the named distorted stream file dist against the named
+// reference stream file ref by running the vmaf tool, and returns the score of every frame
+// and the pooled scores. The streams must have the same geometry, chroma format and number of
+// frames. Streams in chroma formats that vmaf does not read, such as 411 or 444alpha, are
+// converted to 444 in temporary files first.
+func RunVMAF(ref, dist string, o VMAFOptions) (*Scores, error) {
+	rs, err := Open(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	ds, err := Open(dist)
+	if err != nil {
+		return nil, err
+	}
+	defer ds.Close()
+	if rs.Width != ds.Width || rs.Height != ds.Height || rs.Chroma != ds.Chroma {
+		return nil, fmt.Errorf("cannot measure %dx%d %s stream against %dx%d %s reference",
+			ds.Width, ds.Height, ds.Chroma, rs.Width, rs.Height, rs.Chroma)
+	}
+	if !vmafChromas[rs.Chroma] {
+		ref, err = tempConvert(rs, "444")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(ref)
+		dist, err = tempConvert(ds, "444")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(dist)
+	}
+	out, err := os.CreateTemp("", "vmaf-*.json")
+	if err != nil {
+		return nil, err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+	binary := o.Binary
+	if binary == "" {
+		binary = "vmaf"
+	}
+	args := []string{"--reference", ref, "--distorted", dist, "--json", "--output", out.Name()}
+	if o.Model != "" {
+		args = append(args, "--model", "version="+o.Model)
+	}
+	if o.Threads > 0 {
+		args = append(args, "--threads", strconv.Itoa(o.Threads))
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(binary, args...)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, commandError("vmaf", err, stderr.Bytes())
+	}
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		return nil, err
+	}
+	return parseVMAFLog(b)
+}
+
+// parseVMAFLog returns the per-frame VMAF scores in a JSON log written by the vmaf tool.
+func parseVMAFLog(b []byte) (*Scores, error) {
+	var log struct {
+		Frames []struct {
+			FrameNum int                `json:"frameNum"`
+			Metrics  map[string]float64 `json:"metrics"`
+		} `json:"frames"`
+	}
+	err := json.Unmarshal(b, &log)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse vmaf log: %w", err)
+	}
+	s := make([]float64, len(log.Frames))
+	for k, f := range log.Frames {
+		v, ok := f.Metrics["vmaf"]
+		if !ok || f.FrameNum != k {
+			return nil, fmt.Errorf("vmaf log has no score for frame %d", k)
+		}
+		s[k] = v
+	}
+	return newScores(s), nil
+}
+
+// tempConvert writes the frames of stream s, converted to the given chroma format, to a
+// temporary stream file and returns its name.
+func tempConvert(s *Stream, chroma string) (string, error) {
+	f, err := os.CreateTemp("", "y4m-*.y4m")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	f.Close()
+	err = writeConverted(name, s, chroma)
+	if err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
+func writeConverted(name string, s *Stream, chroma string) error {
+	out, err := NewStream(name, s.Width, s.Height)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	out.FrameRate = s.FrameRate
+	out.Interlacing = s.Interlacing
+	out.SampleAspectRatio = s.SampleAspectRatio
+	out.ColorRange = s.ColorRange
+	err = out.SetChroma(chroma)
+	if err != nil {
+		return err
+	}
+	err = out.WriteHeader()
+	if err != nil {
+		return err
+	}
+	for {
+		f, err := s.ParseFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		f, err = f.ConvertChroma(chroma)
+		if err != nil {
+			return err
+		}
+		err = out.WriteFrame(f)
+		if err != nil {
+			return err
+		}
+	}
+	return out.Sync()
+}
@@ -6,15 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"io"
-	"log"
 	"os"
 	"strconv"
 	"strings"
 )
 
 const (
-	streamMagicString = "YUV4MPEG2"
+	streamMagicString      = "YUV4MPEG2"
+	defaultWriteBufferSize = 1 << 20
 )
 
 var (
@@ -35,6 +36,36 @@ type Stream struct {
 	XSubsamplingFactor int
 	YSubsamplingFactor int
 	OriginalHeader     []byte
+	// YSCSS is the value of the XYSCSS tag written by mjpegtools, naming the chroma
+	// subsampling, e.g. "420JPEG". It is empty if the tag is absent.
+	YSCSS string
+	// ColorRange is the sample range given by the XCOLORRANGE tag written by ffmpeg.
+	ColorRange ColorRange
+	// MasteringDisplay is the HDR mastering display color volume given by the
+	// XMASTERING_DISPLAY tag, or nil if the tag is absent.
+	MasteringDisplay *MasteringDisplay
+	// MaxCLL and MaxFALL are the maximum content light level and maximum frame-average light
+	// level in cd/m² given by the XMAXCLL and XMAXFALL tags. They are zero if the tags are
+	// absent.
+	MaxCLL  int
+	MaxFALL int
+	// Recover enables recovery mode, in which ParseFrame drops corrupt frames and resumes at
+	// the next frame header instead of returning an error.
+	Recover bool
+	// Recovered reports the data dropped in recovery mode.
+	Recovered RecoveryStats
+	// Limits bounds the length of the headers parsed from the stream. Streams opened for
+	// reading start with DefaultLimits; the stream header has then already been parsed.
+	Limits     Limits
+	frameIndex int    // number of the next frame to be read, counting from zero
+	mapping    []byte // read-only memory mapping of the file, if opened with OpenMapped
+	w          *bufio.Writer
+	in         *bufio.Reader // source of a stream opened with OpenReader, which cannot seek
+	pos        int64         // number of octets read from in
+	closer     io.Closer     // closed with the stream, such as the connection of OpenConn
+	rd         *bufio.Reader // reused to read frame headers from file
+	headerLen  int64         // length of the stream header in octets
+	discardRaw bool          // set by DiscardRawHeaders
 }
 
 // Frame represents a YCbCr frame with an optional Alpha plane
@@ -72,6 +103,7 @@ type Ratio struct {
 
 var xSubsamplingFactor = map[string]int{
 	"444":      1,
+	"444alpha": 1,
 	"422":      2,
 	"411":      4,
 	"420jpeg":  2,
@@ -81,6 +113,7 @@ var xSubsamplingFactor = map[string]int{
 
 var ySubsamplingFactor = map[string]int{
 	"444":      1,
+	"444alpha": 1,
 	"422":      1,
 	"411":      1,
 	"420jpeg":  2,
@@ -88,29 +121,63 @@ var ySubsamplingFactor = map[string]int{
 	"420paldv": 2,
 }
 
-// Open opens a named file for reading and parses the header.
+// Open opens a named file for reading and parses the header. A file that cannot seek, such
+// as a named pipe or /dev/stdin connected to a pipe, is read as a stream, as with OpenReader:
+// its frames can only be read in order, and methods that seek return ErrNotSeekable.
 func Open(name string) (*Stream, error) {
-	var err error
-	s := new(Stream)
-	s.file, err = os.Open(name)
+	f, err := os.Open(name)
 	if err != nil {
 		return nil, err
 	}
-	err = s.IsY4M()
-	if err != nil {
-		return nil, err
+	var s *Stream
+	if _, serr := f.Seek(0, io.SeekCurrent); serr != nil {
+		s, err = OpenReader(f)
+		if s != nil {
+			s.file = f
+		}
+	} else {
+		s = &Stream{file: f, Limits: DefaultLimits}
+		err = s.readHeader()
 	}
-	err = s.ParseHeader()
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
-	s.XSubsamplingFactor = xSubsamplingFactor[s.Chroma]
-	s.YSubsamplingFactor = ySubsamplingFactor[s.Chroma]
 	return s, nil
 }
 
+// readHeader checks the stream signature and parses the header.
+func (s *Stream) readHeader() error {
+	err := s.IsY4M()
+	if err != nil {
+		return err
+	}
+	return s.ParseHeader()
+}
+
+// SetChroma sets the chroma format of the stream and the corresponding subsampling factors.
+func (s *Stream) SetChroma(chroma string) error {
+	if _, ok := xSubsamplingFactor[chroma]; !ok && chroma != "mono" {
+		return fmt.Errorf("%w: %s", ErrUnsupportedChroma, chroma)
+	}
+	s.Chroma = chroma
+	s.XSubsamplingFactor = xSubsamplingFactor[chroma]
+	s.YSubsamplingFactor = ySubsamplingFactor[chroma]
+	return nil
+}
+
 // IsY4M checks that the stream begins with "YUV4MPEG".
 func (s *Stream) IsY4M() error {
+	if s.in != nil {
+		if s.pos != 0 {
+			return ErrNotSeekable
+		}
+		sb, _ := s.in.Peek(len(streamMagicString))
+		if string(sb) != streamMagicString {
+			return ErrInvalidFormat
+		}
+		return nil
+	}
 	sb := make([]byte, len(streamMagicString))
 	_, err := s.file.Read(sb)
 	if err != nil {
@@ -126,20 +193,41 @@ func (s *Stream) IsY4M() error {
 // ParseHeader parses a Y4M stream header and stores the parsed information in the
 // fields of stream s. The file read offset will be set to the end of the header.
 func (s *Stream) ParseHeader() error {
+	if s.in != nil {
+		return ErrNotSeekable
+	}
 	_, err := s.file.Seek(0, 0)
 	r := bufio.NewReader(s.file)
-	b, err := r.ReadBytes('\n')
+	b, err := readLine(r, s.Limits.MaxHeaderLength)
 	if err != nil {
 		return err
 	}
+	err = s.parseHeaderBytes(append([]byte(nil), b...))
+	if err != nil {
+		return err
+	}
+	// Seek to end of header
+	_, err = s.file.Seek(s.headerLen, 0)
+	if err != nil {
+		return nil
+	}
+	return nil
+}
+
+// parseHeaderBytes parses stream header b, including its terminating '\n', into the fields
+// of stream s, sets the subsampling factors, and checks the frame size against the limits.
+func (s *Stream) parseHeaderBytes(b []byte) error {
+	var err error
 	// Store header byte sequence
 	s.OriginalHeader = b
+	s.headerLen = int64(len(b))
 	// Set defaults
 	s.Chroma = "420jpeg"
 	s.Interlacing = "?"
 	s.FrameRate = &Ratio{0, 0}
 	s.SampleAspectRatio = &Ratio{0, 0}
 	fields := bytes.Fields(b)
+	meta := 0
 	for k := 0; k < len(fields); k++ {
 		field := string(fields[k])
 		key := field[0]
@@ -149,42 +237,58 @@ func (s *Stream) ParseHeader() error {
 			// do nothing
 		case 'W':
 			s.Width, err = strconv.Atoi(val)
-			if err != nil {
-				return err
+			if err != nil || s.Width <= 0 {
+				return fmt.Errorf("%w: invalid width %q", ErrInvalidFormat, val)
 			}
 		case 'H':
 			s.Height, err = strconv.Atoi(val)
-			if err != nil {
-				return err
+			if err != nil || s.Height <= 0 {
+				return fmt.Errorf("%w: invalid height %q", ErrInvalidFormat, val)
 			}
 		case 'F':
 			ratio, err := stringToRatio(val)
-			if err != nil {
-				return err
+			if err != nil || !ratio.valid() {
+				return fmt.Errorf("%w: invalid frame rate %q", ErrInvalidFormat, val)
 			}
 			s.FrameRate = ratio
 		case 'I':
+			if len(val) != 1 || !strings.Contains("ptbm?", val) {
+				return fmt.Errorf("%w: invalid interlacing %q", ErrInvalidFormat, val)
+			}
 			s.Interlacing = val
 		case 'A':
 			ratio, err := stringToRatio(val)
-			if err != nil {
-				return err
+			if err != nil || !ratio.valid() {
+				return fmt.Errorf("%w: invalid sample aspect ratio %q", ErrInvalidFormat, val)
 			}
 			s.SampleAspectRatio = ratio
 		case 'C':
 			s.Chroma = val
 		case 'X':
-			s.Metadata = append(s.Metadata, val)
+			meta += len(val)
+			if s.Limits.MaxMetadata > 0 && meta > s.Limits.MaxMetadata {
+				return fmt.Errorf("%w: stream header has more than %d octets of metadata",
+					ErrLimitExceeded, s.Limits.MaxMetadata)
+			}
+			if !s.parseTag(val) {
+				s.Metadata = append(s.Metadata, val)
+			}
 		default:
 			return fmt.Errorf("Unrecognized stream header field: %c\n", key)
 		}
 	}
-	// Seek to end of header
-	_, err = s.file.Seek(int64(len(s.OriginalHeader)), 0)
+	if s.Width == 0 || s.Height == 0 {
+		return fmt.Errorf("%w: stream header does not give the frame size", ErrInvalidFormat)
+	}
+	err = checkSize(s.Width, s.Height)
 	if err != nil {
-		return nil
+		return err
 	}
-	return nil
+	err = s.SetChroma(s.Chroma)
+	if err != nil {
+		return err
+	}
+	return s.Limits.checkFrameSize(s)
 }
 
 // Header generates a header byte sequence. It may not be identical to the stream's
@@ -197,8 +301,8 @@ func (s *Stream) Header() []byte {
 	b = append(b, []byte(fmt.Sprintf(" I%s", s.Interlacing))...)
 	b = append(b, []byte(fmt.Sprintf(" F%v", s.FrameRate))...)
 	b = append(b, []byte(fmt.Sprintf(" A%v", s.SampleAspectRatio))...)
-	for k := 0; k < len(s.Metadata); k++ {
-		b = append(b, []byte(fmt.Sprintf(" X%s", s.Metadata[k]))...)
+	for _, t := range s.Tags() {
+		b = append(b, []byte(fmt.Sprintf(" X%s", t))...)
 	}
 	b = append(b, byte('\n'))
 	return b
@@ -221,128 +325,304 @@ func stringToRatio(s string) (*Ratio, error) {
 	return &Ratio{N: n, D: d}, nil
 }
 
+// valid reports whether a ratio parsed from a header is usable: both terms positive, or both
+// zero for an unknown value.
+func (r *Ratio) valid() bool {
+	return (r.N > 0 && r.D > 0) || (r.N == 0 && r.D == 0)
+}
+
 func (r *Ratio) String() string {
 	return fmt.Sprintf("%d:%d", r.N, r.D)
 }
 
 // ToFirstFrame sets the read offset of the stream file to the beginning of the first frame.
+// A stream that cannot seek must not have read past the header.
 func (s *Stream) ToFirstFrame() error {
+	if s.in != nil && s.pos == s.headerLen {
+		return nil
+	} else if s.in != nil {
+		return ErrNotSeekable
+	}
 	_, err := s.file.Seek(0, 0)
 	if err != nil {
 		return err
 	}
 	r := bufio.NewReader(s.file)
-	_, err = r.ReadBytes('\x0a')
+	_, err = readLine(r, 0)
 	if err != nil {
 		return err
 	}
 	_, err = s.file.Seek(-int64(r.Buffered()), 1)
+	s.frameIndex = 0
 	return err
 }
 
 // SkipFrame skips to the next frame without parsing or storing data.
 func (s *Stream) SkipFrame() error {
-	err := s.SkipFrameHeader()
+	offset, err := s.offset()
 	if err != nil {
 		return err
 	}
-	_, err = s.file.Seek(s.FrameImageDataSize(), 1)
-	return err
+	err = s.SkipFrameHeader()
+	if err != nil {
+		return err
+	}
+	if s.in != nil {
+		var n int64
+		n, err = io.CopyN(io.Discard, s.in, s.FrameImageDataSize())
+		s.pos += n
+		if err == io.EOF {
+			err = s.frameError(offset, ErrTruncatedFrame)
+		}
+	} else {
+		_, err = s.file.Seek(s.FrameImageDataSize(), 1)
+	}
+	if err != nil {
+		return err
+	}
+	s.frameIndex++
+	return nil
 }
 
 // SkipFrameHeader skips past a frame header.
 func (s *Stream) SkipFrameHeader() error {
-	r := bufio.NewReader(s.file)
-	b, err := r.ReadBytes('\n')
+	offset, err := s.offset()
 	if err != nil {
 		return err
 	}
-	magicString := string(b[0:5])
-	if magicString != "FRAME" {
-		return fmt.Errorf("Did not find expected string \"FRAME\" at start of frame header, found \"%s\"\n", string(b[0:15]))
+	r := s.in
+	if r == nil {
+		r = s.fileReader()
+	}
+	b, err := readLine(r, s.Limits.MaxHeaderLength)
+	s.pos += int64(len(b))
+	if err == io.EOF && len(b) > 0 {
+		return s.frameError(offset, ErrTruncatedFrame)
+	} else if err != nil {
+		return s.frameError(offset, err)
+	}
+	if !bytes.HasPrefix(b, frameMagic) {
+		found := bytes.TrimSuffix(b, []byte{'\n'})
+		if len(found) > 15 {
+			found = found[:15]
+		}
+		return s.frameError(offset, fmt.Errorf("%w: did not find expected string \"FRAME\", found %q",
+			ErrBadFrameHeader, found))
+	}
+	if s.in != nil {
+		return nil
 	}
 	_, err = s.file.Seek(-int64(r.Buffered()), 1)
 	return err
 }
 
-// ParseFrame parses frame header and planar image data and returns a Frame.
+// ParseFrame parses frame header and planar image data and returns a Frame. In recovery mode,
+// corrupt frames are skipped.
 func (s *Stream) ParseFrame() (*Frame, error) {
-	var err error
-	frame := new(Frame)
-	frame.Header, err = s.ParseFrameHeader()
+	if s.Recover {
+		return s.parseFrameRecover()
+	}
+	return s.parseFrame()
+}
+
+func (s *Stream) parseFrame() (*Frame, error) {
+	offset, err := s.offset()
 	if err != nil {
 		return nil, err
 	}
+	if s.mapping != nil {
+		return s.parseMappedFrame(offset)
+	}
+	frame := new(Frame)
+	frame.Header, err = s.parseFrameHeader()
+	if err != nil {
+		return nil, s.frameError(offset, err)
+	}
 	frame.Y, err = s.grabPlane(s.LumaPlaneSize())
 	if err != nil {
-		return nil, err
+		return nil, s.frameError(offset, err)
 	}
 	frame.Cb, err = s.grabPlane(s.ChromaPlaneSize())
 	if err != nil {
-		return nil, err
+		return nil, s.frameError(offset, err)
 	}
 	frame.Cr, err = s.grabPlane(s.ChromaPlaneSize())
 	if err != nil {
-		return nil, err
+		return nil, s.frameError(offset, err)
 	}
 	frame.Alpha, err = s.grabPlane(s.AlphaPlaneSize())
 	if err != nil {
-		return nil, err
+		return nil, s.frameError(offset, err)
 	}
 	frame.Width = s.Width
 	frame.Height = s.Height
 	frame.Chroma = s.Chroma
+	s.frameIndex++
 	return frame, nil
 }
 
 // ParseFrameHeader parses a frame header. A frame header consists of string "FRAME",
 // any number of tagged fields preceded by ' ' separator, and '\n'.
 func (s *Stream) ParseFrameHeader() (*FrameHeader, error) {
-	h := new(FrameHeader)
-	r := bufio.NewReader(s.file)
-	hs, err := r.ReadBytes('\n')
+	offset, err := s.offset()
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.parseFrameHeader()
+	if err != nil {
+		return nil, s.frameError(offset, err)
+	}
+	return h, nil
+}
+
+func (s *Stream) parseFrameHeader() (*FrameHeader, error) {
+	r := s.in
+	if r == nil {
+		r = s.fileReader()
+	}
+	hs, err := readLine(r, s.Limits.MaxHeaderLength)
+	s.pos += int64(len(hs))
+	if err == io.EOF && len(hs) > 0 {
+		return nil, ErrTruncatedFrame
+	} else if err != nil {
+		return nil, err
+	}
+	if s.in == nil {
+		_, err = s.file.Seek(-int64(r.Buffered()), 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !s.discardRaw {
+		hs = append([]byte(nil), hs...)
+	}
+	return s.parseFrameLine(hs)
+}
+
+// parseFrameLine parses frame header hs, including its terminating '\n', and checks it
+// against the limits of the stream. The header retains hs as its raw bytes unless the stream
+// discards them.
+func (s *Stream) parseFrameLine(hs []byte) (*FrameHeader, error) {
+	h, err := parseFrameHeaderBytes(hs)
 	if err != nil {
 		return nil, err
 	}
-	h.Raw = hs
-	hf := bytes.Fields(hs)
-	if len(hf) < 1 {
-		return nil, errors.New("Could not parse frame header")
+	err = s.Limits.checkMetadata(h.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	s.dropRaw(h)
+	return h, nil
+}
+
+// DiscardRawHeaders stops the stream from retaining raw header bytes, which long-running
+// pipelines that never use them need not hold in memory: OriginalHeader is released, and the
+// headers of frames parsed afterwards have no Raw bytes. Such frame headers are written as
+// serialized by FrameHeader.Bytes, which keeps the I field and metadata but drops any other
+// fields. Copies made with Trim, CopyFrames and WriteTo remain verbatim.
+func (s *Stream) DiscardRawHeaders() {
+	s.discardRaw = true
+	s.OriginalHeader = nil
+}
+
+// dropRaw removes the raw bytes of frame header h if the stream discards them.
+func (s *Stream) dropRaw(h *FrameHeader) {
+	if s.discardRaw {
+		h.Raw = nil
 	}
-	magicString := string(hf[0])
-	if magicString == "FRAME" {
-		h.MagicString = magicString
+}
+
+// fileReader returns a buffered reader of the stream file from its read offset, reusing the
+// buffer of earlier calls. The file offset must be moved back by the number of octets left
+// buffered once reading is done.
+func (s *Stream) fileReader() *bufio.Reader {
+	if s.rd == nil {
+		s.rd = bufio.NewReader(s.file)
 	} else {
-		return nil, errors.New("Did not find expected magic string \"FRAME\" when parsing frame header")
+		s.rd.Reset(s.file)
 	}
-	for k := 1; k < len(hf); k++ {
-		field := string(hf[k])
+	return s.rd
+}
+
+// offset returns the read offset of the stream.
+func (s *Stream) offset() (int64, error) {
+	if s.in != nil {
+		return s.pos, nil
+	}
+	return s.file.Seek(0, 1)
+}
+
+// frameMagic begins every frame header.
+var frameMagic = []byte("FRAME")
+
+// parseFrameHeaderBytes parses frame header hs, including its terminating '\n'. The fields
+// are parsed in place; only metadata values are copied into strings.
+func parseFrameHeaderBytes(hs []byte) (*FrameHeader, error) {
+	field, rest := nextField(hs)
+	if len(field) == 0 {
+		return nil, fmt.Errorf("%w: empty header", ErrBadFrameHeader)
+	}
+	if !bytes.Equal(field, frameMagic) {
+		return nil, fmt.Errorf("%w: did not find expected magic string \"FRAME\"", ErrBadFrameHeader)
+	}
+	h := &FrameHeader{MagicString: "FRAME", Raw: hs}
+	for {
+		field, rest = nextField(rest)
+		if len(field) == 0 {
+			return h, nil
+		}
 		key := field[0]
 		val := field[1:]
 		switch key {
 		case 'I':
 			if len(val) != 3 {
-				return nil, errors.New("Frame framing/sampling field does not have expected length of 3")
+				return nil, fmt.Errorf("%w: framing/sampling field does not have expected length of 3",
+					ErrBadFrameHeader)
 			}
-			x := val[0]
-			if x != 't' && x != 'T' && x != 'b' && x != 'B' && x != '1' && x != '2' && x != '3' {
-				return nil, fmt.Errorf("Frame presentation subfield has unexpected value %c\n", x)
-			}
-			y := val[1]
-			if y != 'p' && y != 'i' {
-				return nil, fmt.Errorf("Frame temporal sampling subfield has unexpected value %c\n", y)
-			}
-			z := val[2]
-			if z != 'p' && z != 'i' && z != '?' {
-				return nil, fmt.Errorf("Frame spatial sampling subfield has unexpected value %c\n", z)
+			err := checkIField(val[0], val[1], val[2])
+			if err != nil {
+				return nil, err
 			}
-			h.I = &IField{Spatial: z, Temporal: y, Presentation: x}
+			h.I = &IField{Spatial: val[2], Temporal: val[1], Presentation: val[0]}
 		case 'X':
-			h.Metadata = append(h.Metadata, val)
+			h.Metadata = append(h.Metadata, string(val))
 		}
 	}
-	_, err = s.file.Seek(-int64(r.Buffered()), 1)
-	return h, nil
+}
+
+// nextField returns the first field of b, delimited by white space as with bytes.Fields, and
+// the rest of b after it. The field is empty if b holds no more fields.
+func nextField(b []byte) (field, rest []byte) {
+	k := 0
+	for k < len(b) && isSpace(b[k]) {
+		k++
+	}
+	j := k
+	for j < len(b) && !isSpace(b[j]) {
+		j++
+	}
+	return b[k:j], b[j:]
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// Bytes serializes the frame header: the magic string, the I field if present, and any X
+// metadata fields, terminated by '\n'.
+func (h *FrameHeader) Bytes() []byte {
+	magicString := h.MagicString
+	if magicString == "" {
+		magicString = "FRAME"
+	}
+	b := []byte(magicString)
+	if h.I != nil {
+		b = append(b, " I"+h.I.String()...)
+	}
+	for _, m := range h.Metadata {
+		b = append(b, " X"+m...)
+	}
+	return append(b, '\n')
 }
 
 func (s *Stream) grabPlane(size int) ([]byte, error) {
@@ -350,8 +630,18 @@ func (s *Stream) grabPlane(size int) ([]byte, error) {
 		return nil, nil
 	}
 	plane := make([]byte, size)
-	_, err := io.ReadFull(s.file, plane)
-	if err != nil {
+	var err error
+	if s.in != nil {
+		var n int
+		n, err = io.ReadFull(s.in, plane)
+		s.pos += int64(n)
+	} else {
+		_, err = io.ReadFull(s.file, plane)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// The frame header has been read, so the stream ending here truncates the frame
+		return nil, ErrTruncatedFrame
+	} else if err != nil {
 		return nil, err
 	}
 	return plane, nil
@@ -359,7 +649,7 @@ func (s *Stream) grabPlane(size int) ([]byte, error) {
 
 // LumaPlaneSize returns the size of the luma plane in octets.
 func (s *Stream) LumaPlaneSize() int {
-	return s.Height * s.Width
+	return int(int64(s.Height) * int64(s.Width))
 }
 
 // ChromaPlaneSize returns the size of a single chroma plane in octets.
@@ -367,23 +657,33 @@ func (s *Stream) ChromaPlaneSize() int {
 	if s.Chroma == "mono" {
 		return 0
 	}
-	return s.Width * s.Height / s.XSubsamplingFactor / s.YSubsamplingFactor
+	cw, ch := chromaDims(s.Width, s.Height, s.Chroma)
+	return int(int64(cw) * int64(ch))
 }
 
 // AlphaPlaneSize returns the size of the alpha plane in octets.
 func (s *Stream) AlphaPlaneSize() int {
 	if s.Chroma == "444alpha" {
-		return s.Width * s.Height
+		return s.LumaPlaneSize()
 	}
 	return 0
 }
 
-// CountFrames counts the number of frames in the stream.
+// CountFrames counts the number of frames in the stream. When the frames have bare "FRAME"
+// headers and a constant size, the count is computed from the file size; otherwise the
+// stream is scanned.
 func (s *Stream) CountFrames() (int, error) {
+	if s.in != nil {
+		return -1, ErrNotSeekable
+	}
+	if n, ok := s.countFramesFast(); ok {
+		return n, nil
+	}
 	initPos, err := s.file.Seek(0, 1)
 	if err != nil {
 		return -1, err
 	}
+	initFrame := s.frameIndex
 	_, err = s.file.Seek(0, 0)
 	if err != nil {
 		return -1, err
@@ -406,24 +706,139 @@ func (s *Stream) CountFrames() (int, error) {
 	if err != nil {
 		return -1, err
 	}
+	s.frameIndex = initFrame
 	return frameCounter, nil
 }
 
+// countFramesFast computes the number of frames from the file size, assuming every frame has
+// a bare "FRAME" header. It reports false if the file size or the headers of the first two and
+// the last frames are inconsistent with that assumption.
+func (s *Stream) countFramesFast() (int, bool) {
+	if s.headerLen == 0 {
+		return 0, false
+	}
+	fi, err := s.file.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return 0, false
+	}
+	bare := []byte("FRAME\n")
+	frameSize := int64(len(bare)) + s.FrameImageDataSize()
+	start := s.headerLen
+	size := fi.Size() - start
+	if size < 0 || size%frameSize != 0 {
+		return 0, false
+	}
+	n := size / frameSize
+	b := make([]byte, len(bare))
+	for _, k := range []int64{0, 1, n - 1} {
+		if k < 0 || k >= n {
+			continue
+		}
+		_, err := s.file.ReadAt(b, start+k*frameSize)
+		if err != nil || !bytes.Equal(b, bare) {
+			return 0, false
+		}
+	}
+	return int(n), true
+}
+
 // FrameImageDataSize returns the total number of octets of planar image data per frame
 func (s *Stream) FrameImageDataSize() int64 {
-	return int64(s.LumaPlaneSize() + 2*s.ChromaPlaneSize() + s.AlphaPlaneSize())
+	return int64(s.LumaPlaneSize()) + 2*int64(s.ChromaPlaneSize()) + int64(s.AlphaPlaneSize())
+}
+
+// subsampling returns the horizontal and vertical chroma subsampling factors of the chroma
+// format. Mono has no chroma planes and is reported as unsubsampled.
+func subsampling(chroma string) (xss, yss int, err error) {
+	if chroma == "mono" {
+		return 1, 1, nil
+	}
+	xss, ok := xSubsamplingFactor[chroma]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %s", ErrUnsupportedChroma, chroma)
+	}
+	return xss, ySubsamplingFactor[chroma], nil
 }
 
+// CropAlignment selects how Frame.CropAligned treats crop offsets and sizes that are not
+// multiples of the chroma subsampling factors.
+type CropAlignment int
+
+const (
+	// AlignStrict rejects unaligned offsets and sizes with an error.
+	AlignStrict CropAlignment = iota
+	// AlignDown rounds unaligned offsets and sizes down to the nearest aligned values.
+	AlignDown
+)
+
 // Crop crops the frame image to width w and height h, offset from the top left of the
 // original frame horizontally by xOffset, and vertically by yOffset. The frame's w and h
-// fields are updated.
+// fields are updated. Offsets and sizes must be multiples of the chroma subsampling factors,
+// and for frames whose I field marks them as interlaced, vertical offsets and heights must be
+// multiples of twice the vertical factor, so that the chroma lines of each field stay
+// together. An odd vertical offset turns the top field into the bottom field, so the field
+// order of the frame's I field is swapped. Frames without an I field are cropped as
+// progressive; use Stream.CropFrame to crop them according to their stream's interlacing.
 func (f *Frame) Crop(w, h, xOffset, yOffset int) error {
+	_, err := f.CropAligned(w, h, xOffset, yOffset, AlignStrict)
+	return err
+}
+
+// CropAligned is like Crop, but treats offsets and sizes that are not multiples of the chroma
+// subsampling factors according to policy a. It returns the region of the original frame that
+// was kept.
+func (f *Frame) CropAligned(w, h, xOffset, yOffset int, a CropAlignment) (image.Rectangle, error) {
+	return f.cropAligned(w, h, xOffset, yOffset, a, nil)
+}
+
+// CropFrame crops frame f, read from the stream, as Frame.CropAligned does, but treats a frame
+// without an I field as having the one implied by the stream's interlacing mode, as returned
+// by IField. The frames of an It or Ib stream with bare "FRAME" headers are therefore cropped
+// with the alignment of interlaced frames, keeping each field's chroma with its luma. An odd
+// vertical offset gives such a frame the stream's I field with the field order swapped, which
+// records that its top field has become the bottom field; the output stream should then be
+// mixed-mode or have its field order swapped too.
+func (s *Stream) CropFrame(f *Frame, w, h, xOffset, yOffset int, a CropAlignment) (image.Rectangle, error) {
+	return f.cropAligned(w, h, xOffset, yOffset, a, s.IField())
+}
+
+// cropAligned implements CropAligned for a frame whose stream implies I field implied, or nil
+// if the stream's interlacing is unknown or ignored.
+func (f *Frame) cropAligned(w, h, xOffset, yOffset int, a CropAlignment, implied *IField) (image.Rectangle, error) {
+	xss, yss, err := subsampling(f.Chroma)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	if xOffset < 0 || yOffset < 0 {
+		return image.Rectangle{}, fmt.Errorf("offsets (%d, %d) cannot be negative", xOffset, yOffset)
+	}
+	i := f.frameI(implied)
+	interlaced := i != nil && i.Temporal == SamplingInterlaced
+	ya := fieldAlignment(yss, interlaced)
+	if xOffset%xss != 0 || yOffset%ya != 0 || w%xss != 0 || h%ya != 0 {
+		if a != AlignDown {
+			kind := f.Chroma
+			if ya != yss {
+				kind = "interlaced " + kind
+			}
+			return image.Rectangle{}, fmt.Errorf(
+				"cropped size %dx%d at offset (%d, %d) is not aligned to %s chroma subsampling (%dx%d)",
+				w, h, xOffset, yOffset, kind, xss, ya)
+		}
+		xOffset -= xOffset % xss
+		yOffset -= yOffset % ya
+		w -= w % xss
+		h -= h % ya
+	}
+	if w < 1 || h < 1 {
+		return image.Rectangle{}, fmt.Errorf("cropped size %dx%d must be at least %dx%d", w, h, xss, yss)
+	}
 	if w+xOffset > f.Width {
-		return fmt.Errorf("cropped width + x offset (%d) cannot exceed original width (%d)",
+		return image.Rectangle{}, fmt.Errorf("cropped width + x offset (%d) cannot exceed original width (%d)",
 			w+xOffset, f.Width)
 	}
 	if h+yOffset > f.Height {
-		return fmt.Errorf("cropped height + y offset (%d) cannot exceed original height (%d)",
+		return image.Rectangle{}, fmt.Errorf("cropped height + y offset (%d) cannot exceed original height (%d)",
 			h+yOffset, f.Height)
 	}
 	newY := make([]byte, 0, w*h)
@@ -434,19 +849,20 @@ func (f *Frame) Crop(w, h, xOffset, yOffset int) error {
 		newY = append(newY, f.Y[x0:x1]...)
 	}
 	f.Y = newY
-	xss := xSubsamplingFactor[f.Chroma]
-	yss := ySubsamplingFactor[f.Chroma]
-	newCb := make([]byte, 0, w/xss*h/yss)
-	newCr := make([]byte, 0, w/xss*h/yss)
-	for y := 0; y < h/yss; y++ {
-		yt := y + yOffset/yss
-		x0 := yt*f.Width/xss + xOffset/xss
-		x1 := x0 + w/xss
-		newCb = append(newCb, f.Cb[x0:x1]...)
-		newCr = append(newCr, f.Cr[x0:x1]...)
-	}
-	f.Cb = newCb
-	f.Cr = newCr
+	if len(f.Cb) > 0 {
+		cw0, _ := chromaDims(f.Width, f.Height, f.Chroma)
+		newCb := make([]byte, 0, w/xss*h/yss)
+		newCr := make([]byte, 0, w/xss*h/yss)
+		for y := 0; y < h/yss; y++ {
+			yt := y + yOffset/yss
+			x0 := yt*cw0 + xOffset/xss
+			x1 := x0 + w/xss
+			newCb = append(newCb, f.Cb[x0:x1]...)
+			newCr = append(newCr, f.Cr[x0:x1]...)
+		}
+		f.Cb = newCb
+		f.Cr = newCr
+	}
 	if len(f.Alpha) > 0 {
 		newAlpha := make([]byte, 0, w*h)
 		for y := 0; y < h; y++ {
@@ -459,12 +875,103 @@ func (f *Frame) Crop(w, h, xOffset, yOffset int) error {
 	}
 	f.Width = w
 	f.Height = h
+	hasI := f.Header != nil && f.Header.I != nil
+	if yOffset%2 == 1 && (hasI || interlaced) {
+		hdr := f.Header.Copy()
+		if hdr == nil {
+			hdr = &FrameHeader{MagicString: "FRAME"}
+		}
+		swapped := *i
+		swapped.Presentation = swapPresentation(swapped.Presentation)
+		hdr.I = &swapped
+		hdr.Raw = hdr.Bytes()
+		f.Header = hdr
+	}
+	return image.Rect(xOffset, yOffset, xOffset+w, yOffset+h), nil
+}
+
+// Pad places the frame image on a larger canvas of width w and height h filled with color
+// fill, offset from the top left of the canvas horizontally by xOffset, and vertically by
+// yOffset. It is the inverse of Crop. Any alpha plane is padded with opaque samples. The
+// frame's w and h fields are updated.
+func (f *Frame) Pad(w, h, xOffset, yOffset int, fill color.YCbCr) error {
+	if xOffset < 0 || yOffset < 0 {
+		return fmt.Errorf("offsets (%d, %d) cannot be negative", xOffset, yOffset)
+	}
+	if f.Width+xOffset > w {
+		return fmt.Errorf("original width + x offset (%d) cannot exceed padded width (%d)",
+			f.Width+xOffset, w)
+	}
+	if f.Height+yOffset > h {
+		return fmt.Errorf("original height + y offset (%d) cannot exceed padded height (%d)",
+			f.Height+yOffset, h)
+	}
+	xss, yss, err := subsampling(f.Chroma)
+	if err != nil {
+		return err
+	}
+	if len(f.Cb) > 0 && (w%xss != 0 || h%yss != 0 || xOffset%xss != 0 || yOffset%yss != 0) {
+		return fmt.Errorf("padded size and offsets must be multiples of %s chroma subsampling (%dx%d)",
+			f.Chroma, xss, yss)
+	}
+	f.Y = padPlane(f.Y, f.Width, f.Height, w, h, xOffset, yOffset, fill.Y)
+	if len(f.Cb) > 0 {
+		cw0, ch0 := chromaDims(f.Width, f.Height, f.Chroma)
+		f.Cb = padPlane(f.Cb, cw0, ch0, w/xss, h/yss, xOffset/xss, yOffset/yss, fill.Cb)
+		f.Cr = padPlane(f.Cr, cw0, ch0, w/xss, h/yss, xOffset/xss, yOffset/yss, fill.Cr)
+	}
+	if len(f.Alpha) > 0 {
+		f.Alpha = padPlane(f.Alpha, f.Width, f.Height, w, h, xOffset, yOffset, 0xff)
+	}
+	f.Width = w
+	f.Height = h
 	return nil
 }
 
-// Image converts the frame planar image data into a YCbCr image. In the case that alpha
-// plane is present, an NYCbCrA image is created.
-func (f *Frame) Image() image.Image {
+// Fit centres the frame image on a canvas of width w and height h, cropping it where it is
+// larger and padding it with color fill where it is smaller. Offsets are rounded down to
+// multiples of the chroma subsampling. The frame's w and h fields are updated.
+func (f *Frame) Fit(w, h int, fill color.YCbCr) error {
+	xss, yss, err := subsampling(f.Chroma)
+	if err != nil {
+		return err
+	}
+	cw, ch := minInt(f.Width, w), minInt(f.Height, h)
+	if cw < f.Width || ch < f.Height {
+		_, err = f.CropAligned(cw, ch, (f.Width-cw)/2, (f.Height-ch)/2, AlignDown)
+		if err != nil {
+			return err
+		}
+	}
+	if f.Width < w || f.Height < h {
+		x, y := (w-f.Width)/2, (h-f.Height)/2
+		return f.Pad(w, h, x-x%xss, y-y%yss, fill)
+	}
+	return nil
+}
+
+// padPlane copies plane p, of width w0 and height h0, into a new plane of width w and height
+// h filled with value v, at offset (xOffset, yOffset).
+func padPlane(p []byte, w0, h0, w, h, xOffset, yOffset int, v byte) []byte {
+	out := make([]byte, w*h)
+	for k := range out {
+		out[k] = v
+	}
+	for y := 0; y < h0; y++ {
+		copy(out[(y+yOffset)*w+xOffset:], p[y*w0:(y+1)*w0])
+	}
+	return out
+}
+
+// Image converts the frame planar image data into an image that shares the frame's planes.
+// Mono frames are converted to a Gray image. In the case that alpha plane is present, an
+// NYCbCrA image is created. An error wrapping ErrUnsupportedChroma is returned for unknown
+// chroma formats.
+func (f *Frame) Image() (image.Image, error) {
+	r := image.Rect(0, 0, f.Width, f.Height)
+	if f.Chroma == "mono" {
+		return &image.Gray{Pix: f.Y, Stride: f.Width, Rect: r}, nil
+	}
 	var ssr image.YCbCrSubsampleRatio
 	switch f.Chroma {
 	case "444", "444alpha":
@@ -475,28 +982,23 @@ func (f *Frame) Image() image.Image {
 		ssr = image.YCbCrSubsampleRatio420
 	case "411":
 		ssr = image.YCbCrSubsampleRatio411
-	case "mono":
-		log.Fatal("Mono images should be handled by another function")
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedChroma, f.Chroma)
+	}
+	cw, _ := chromaDims(f.Width, f.Height, f.Chroma)
+	img := image.YCbCr{
+		Y:              f.Y,
+		Cb:             f.Cb,
+		Cr:             f.Cr,
+		YStride:        f.Width,
+		CStride:        cw,
+		SubsampleRatio: ssr,
+		Rect:           r,
 	}
-	r := image.Rect(0, 0, f.Width, f.Height)
 	if len(f.Alpha) > 0 {
-		img := image.NewNYCbCrA(r, ssr)
-		img.YCbCr.Y = f.Y
-		img.YCbCr.Cb = f.Cb
-		img.YCbCr.Cr = f.Cr
-		img.A = f.Alpha
-		return img
-	} else if f.Chroma == "mono" {
-		img := image.NewGray(r)
-		img.Pix = f.Y
-		return img
-	} else {
-		img := image.NewYCbCr(r, ssr)
-		img.Y = f.Y
-		img.Cb = f.Cb
-		img.Cr = f.Cr
-		return img
+		return &image.NYCbCrA{YCbCr: img, A: f.Alpha, AStride: f.Width}, nil
 	}
+	return &img, nil
 }
 
 // PrintHeaderInfo prints header info to stdout.
@@ -507,64 +1009,197 @@ func (s *Stream) PrintHeaderInfo() {
 	fmt.Printf("  Frame rate: %v\n", s.FrameRate)
 	fmt.Printf("  Interlacing: %s\n", s.Interlacing)
 	fmt.Printf("  SampleAspectRatio: %v\n", s.SampleAspectRatio)
+	if dar := s.DisplayAspectRatio(); dar != nil {
+		fmt.Printf("  DisplayAspectRatio: %v\n", dar)
+	}
 	fmt.Printf("  Chroma: %s\n", s.Chroma)
+	if s.YSCSS != "" {
+		fmt.Printf("  YSCSS: %s\n", s.YSCSS)
+	}
+	if s.ColorRange != ColorRangeUnspecified {
+		fmt.Printf("  ColorRange: %v\n", s.ColorRange)
+	}
+	if s.MasteringDisplay != nil {
+		fmt.Printf("  MasteringDisplay: %v\n", s.MasteringDisplay)
+	}
+	if s.MaxCLL != 0 || s.MaxFALL != 0 {
+		fmt.Printf("  MaxCLL: %d MaxFALL: %d\n", s.MaxCLL, s.MaxFALL)
+	}
 	fmt.Printf("  Metadata: %v\n", s.Metadata)
 }
 
-// NewStream creates a new named stream file with width w and height h. The stream file can be
-// synced with the Sync method and closed with the Close method.
+// NewStream creates a new named stream file with width w and height h. Writes to the stream
+// are buffered; the buffer can be flushed with the Flush method, the stream file can be synced
+// with the Sync method, and closed with the Close method.
 func NewStream(name string, w, h int) (*Stream, error) {
+	return NewStreamSize(name, w, h, defaultWriteBufferSize)
+}
+
+// NewStreamSize is like NewStream, but the write buffer has at least size octets. A size of
+// zero disables buffering.
+func NewStreamSize(name string, w, h, size int) (*Stream, error) {
 	f, err := os.Create(name)
 	if err != nil {
 		return nil, err
 	}
 	s := new(Stream)
 	s.file = f
+	if size > 0 {
+		s.w = bufio.NewWriterSize(f, size)
+	}
 	s.Width = w
 	s.Height = h
 	return s, nil
 }
 
+// writer returns the destination for writes to the stream file.
+func (s *Stream) writer() io.Writer {
+	if s.w != nil {
+		return s.w
+	}
+	return s.file
+}
+
 // WriteHeader writes a stream header byte sequence to the file stream
 func (s *Stream) WriteHeader() error {
 	h := s.Header()
-	_, err := s.file.Write(h)
+	_, err := s.writer().Write(h)
 	return err
 }
 
-// WriteFrameHeader writes a frame header byte sequence to the file stream
+// WriteFrameHeader writes a frame header byte sequence to the file stream. The header's raw
+// bytes are written if present; otherwise the header is serialized with Bytes. The frames of a
+// mixed-mode stream must have an I field.
 func (s *Stream) WriteFrameHeader(frame *Frame) error {
-	_, err := s.file.Write(frame.Header.Raw)
+	err := s.checkI(frame)
+	if err != nil {
+		return err
+	}
+	_, err = s.writer().Write(frameHeaderBytes(frame.Header))
 	return err
 }
 
+// frameHeaderBytes returns the raw bytes of frame header h if present, or else its
+// serialization. A nil header is written as a bare "FRAME" header.
+func frameHeaderBytes(h *FrameHeader) []byte {
+	if h == nil {
+		return []byte("FRAME\n")
+	}
+	if len(h.Raw) > 0 {
+		return h.Raw
+	}
+	return h.Bytes()
+}
+
 // WriteFrameData writes planar video data to the file stream
 func (s *Stream) WriteFrameData(frame *Frame) error {
-	_, err := s.file.Write(frame.Y)
+	w := s.writer()
+	_, err := w.Write(frame.Y)
 	if err != nil {
 		return err
 	}
-	_, err = s.file.Write(frame.Cb)
+	_, err = w.Write(frame.Cb)
 	if err != nil {
 		return err
 	}
-	_, err = s.file.Write(frame.Cr)
+	_, err = w.Write(frame.Cr)
 	if err != nil {
 		return err
 	}
-	_, err = s.file.Write(frame.Alpha)
+	_, err = w.Write(frame.Alpha)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// Sync commits the current contents of the stream file to stable storage
+// WriteFrame checks that the frame's chroma format and planes match the stream and writes the
+// frame header and planar video data to the file stream in a single write. A frame with no
+// chroma format set is checked against the stream geometry only. If the frame has no header,
+// a bare "FRAME" header is written. The frames of a mixed-mode stream must have an I field,
+// which can be set with SetI.
+func (s *Stream) WriteFrame(frame *Frame) error {
+	if frame.Width != s.Width || frame.Height != s.Height {
+		return fmt.Errorf("frame size %dx%d does not match stream size %dx%d",
+			frame.Width, frame.Height, s.Width, s.Height)
+	}
+	if frame.Chroma != "" && frame.Chroma != s.Chroma {
+		return fmt.Errorf("frame chroma format %s does not match stream chroma format %s",
+			frame.Chroma, s.Chroma)
+	}
+	planes := []struct {
+		name string
+		data []byte
+		size int
+	}{
+		{"Y", frame.Y, s.LumaPlaneSize()},
+		{"Cb", frame.Cb, s.ChromaPlaneSize()},
+		{"Cr", frame.Cr, s.ChromaPlaneSize()},
+		{"Alpha", frame.Alpha, s.AlphaPlaneSize()},
+	}
+	for _, p := range planes {
+		if len(p.data) != p.size {
+			return fmt.Errorf("%s plane has %d octets, expected %d", p.name, len(p.data), p.size)
+		}
+	}
+	err := s.checkI(frame)
+	if err != nil {
+		return err
+	}
+	header := frameHeaderBytes(frame.Header)
+	b := make([]byte, 0, int64(len(header))+s.FrameImageDataSize())
+	b = append(b, header...)
+	for _, p := range planes {
+		b = append(b, p.data...)
+	}
+	_, err = s.writer().Write(b)
+	return err
+}
+
+// Flush writes any buffered data to the stream file.
+func (s *Stream) Flush() error {
+	if s.w == nil {
+		return nil
+	}
+	return s.w.Flush()
+}
+
+// Sync flushes buffered data and commits the current contents of the stream file to stable
+// storage
 func (s *Stream) Sync() error {
+	err := s.Flush()
+	if err != nil || s.file == nil {
+		return err
+	}
 	return s.file.Sync()
 }
 
-// Close closes the stream file
+// Close flushes buffered data and closes the stream file. The reader or writer of a stream
+// created with OpenReader or NewStreamWriter is not closed, but the connection of a stream
+// created with OpenConn or NewConnWriter and the response body of OpenURL are.
 func (s *Stream) Close() error {
+	if s.closer != nil {
+		err := s.Flush()
+		if cerr := s.closer.Close(); err == nil {
+			err = cerr
+		}
+		s.closer = nil
+		return err
+	}
+	if s.file == nil {
+		return s.Flush()
+	}
+	if err := s.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	if s.mapping != nil {
+		err := munmap(s.mapping)
+		s.mapping = nil
+		if err != nil {
+			s.file.Close()
+			return err
+		}
+	}
 	return s.file.Close()
 }